@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is a message published to or consumed from a topic of a message broker
+type Message struct {
+	Key     string
+	Value   string
+	Headers map[string]string
+}
+
+// Broker is a message broker used to publish messages before the request
+// and to check messages published by the tested service
+type Broker interface {
+	// Publish publishes messages to the topic
+	Publish(ctx context.Context, topic string, messages []Message) error
+	// Subscribe starts collecting messages published to the topic, messages collected before are forgotten
+	Subscribe(ctx context.Context, topic string) error
+	// Messages returns messages published to the topic since Subscribe was called
+	Messages(ctx context.Context, topic string) ([]Message, error)
+}
+
+// InMemory is a broker which keeps messages in memory, it is useful to test services with a stand-in broker
+type InMemory struct {
+	mu     sync.Mutex
+	topics map[string][]Message
+	marks  map[string]int
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{
+		topics: make(map[string][]Message),
+		marks:  make(map[string]int),
+	}
+}
+
+func (b *InMemory) Publish(_ context.Context, topic string, messages []Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.topics[topic] = append(b.topics[topic], messages...)
+	return nil
+}
+
+func (b *InMemory) Subscribe(_ context.Context, topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.marks[topic] = len(b.topics[topic])
+	return nil
+}
+
+func (b *InMemory) Messages(_ context.Context, topic string) ([]Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages := b.topics[topic][b.marks[topic]:]
+	res := make([]Message, len(messages))
+	copy(res, messages)
+	return res, nil
+}
@@ -0,0 +1,200 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/lamoda/gonkey/broker"
+)
+
+// Broker publishes and reads messages of Kafka topics
+type Broker struct {
+	brokers []string
+	client  *kafka.Client
+	writer  *kafka.Writer
+
+	mu sync.Mutex
+	// offsets of the topic partitions at the moment of subscription
+	marks map[string]map[int]int64
+}
+
+// readIdleTimeout is the time to wait for the next message of the partition before the range
+// is considered read, the offsets taken by transaction markers and compacted messages are never returned
+const readIdleTimeout = time.Second
+
+type partitionOffsets struct {
+	first int64
+	last  int64
+}
+
+func New(brokers ...string) *Broker {
+	addr := kafka.TCP(brokers...)
+	return &Broker{
+		brokers: brokers,
+		client:  &kafka.Client{Addr: addr},
+		writer: &kafka.Writer{
+			Addr:                   addr,
+			Balancer:               &kafka.Hash{},
+			BatchTimeout:           10 * time.Millisecond,
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+		marks: make(map[string]map[int]int64),
+	}
+}
+
+func (b *Broker) Publish(ctx context.Context, topic string, messages []broker.Message) error {
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		km := kafka.Message{
+			Topic: topic,
+			Value: []byte(m.Value),
+		}
+		if m.Key != "" {
+			km.Key = []byte(m.Key)
+		}
+		for k, v := range m.Headers {
+			km.Headers = append(km.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		kafkaMessages = append(kafkaMessages, km)
+	}
+	return b.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+func (b *Broker) Subscribe(ctx context.Context, topic string) error {
+	offsets, err := b.offsets(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	marks := make(map[int]int64, len(offsets))
+	for partition, o := range offsets {
+		marks[partition] = o.last
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.marks[topic] = marks
+	return nil
+}
+
+// Messages reads messages of the topic partition by partition,
+// so messages of different partitions are not ordered relative to each other
+func (b *Broker) Messages(ctx context.Context, topic string) ([]broker.Message, error) {
+	offsets, err := b.offsets(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	marks := b.marks[topic]
+	b.mu.Unlock()
+
+	var messages []broker.Message
+	for partition, o := range offsets {
+		start := o.first
+		if mark, ok := marks[partition]; ok && mark > start {
+			start = mark
+		}
+		if start >= o.last {
+			continue
+		}
+		partitionMessages, err := b.read(ctx, topic, partition, start, o.last)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, partitionMessages...)
+	}
+	return messages, nil
+}
+
+func (b *Broker) Close() error {
+	return b.writer.Close()
+}
+
+// read reads messages of the partition in the range [start, end), it stops at the end of the range,
+// when the reader has caught up with the high water mark or when no more messages come
+func (b *Broker) read(ctx context.Context, topic string, partition int, start, end int64) ([]broker.Message, error) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   b.brokers,
+		Topic:     topic,
+		Partition: partition,
+		MaxWait:   100 * time.Millisecond,
+	})
+	defer r.Close()
+
+	if err := r.SetOffset(start); err != nil {
+		return nil, err
+	}
+
+	var messages []broker.Message
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, readIdleTimeout)
+		m, err := r.ReadMessage(readCtx)
+		cancel()
+		if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read partition %d of topic %s: %w", partition, topic, err)
+		}
+
+		message := broker.Message{
+			Key:   string(m.Key),
+			Value: string(m.Value),
+		}
+		if len(m.Headers) != 0 {
+			message.Headers = make(map[string]string, len(m.Headers))
+			for _, h := range m.Headers {
+				message.Headers[h.Key] = string(h.Value)
+			}
+		}
+		messages = append(messages, message)
+
+		if m.Offset >= end-1 || r.Lag() <= 0 {
+			return messages, nil
+		}
+	}
+}
+
+// offsets returns offsets of all partitions of the topic, the topic which doesn't exist has no partitions
+func (b *Broker) offsets(ctx context.Context, topic string) (map[int]partitionOffsets, error) {
+	meta, err := b.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.Topics) == 0 {
+		return nil, nil
+	}
+	if err := meta.Topics[0].Error; err != nil {
+		if errors.Is(err, kafka.UnknownTopicOrPartition) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var requests []kafka.OffsetRequest
+	for _, p := range meta.Topics[0].Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+	resp, err := b.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]partitionOffsets)
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		offsets[p.Partition] = partitionOffsets{first: p.FirstOffset, last: p.LastOffset}
+	}
+	return offsets, nil
+}
@@ -5,3 +5,10 @@ import "github.com/lamoda/gonkey/models"
 type CheckerInterface interface {
 	Check(models.TestInterface, *models.Result) ([]error, error)
 }
+
+// PreparingChecker is a checker which has to be prepared before the request of the test is sent,
+// e.g. to start collecting events produced by the request
+type PreparingChecker interface {
+	CheckerInterface
+	Prepare(models.TestInterface) error
+}
@@ -0,0 +1,141 @@
+package response_broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/lamoda/gonkey/broker"
+	"github.com/lamoda/gonkey/checker"
+	"github.com/lamoda/gonkey/compare"
+	"github.com/lamoda/gonkey/models"
+)
+
+// DefaultTimeout is the time given to subscribe to the topics or to read their messages
+const DefaultTimeout = 10 * time.Second
+
+type ResponseBrokerChecker struct {
+	broker  broker.Broker
+	timeout time.Duration
+}
+
+func NewChecker(b broker.Broker) checker.PreparingChecker {
+	return NewCheckerWithTimeout(b, DefaultTimeout)
+}
+
+// NewCheckerWithTimeout returns the checker which fails the test if the broker
+// doesn't respond in time, DefaultTimeout is used if the timeout is not positive
+func NewCheckerWithTimeout(b broker.Broker, timeout time.Duration) checker.PreparingChecker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &ResponseBrokerChecker{
+		broker:  b,
+		timeout: timeout,
+	}
+}
+
+// Prepare subscribes to the checked topics, so only messages published during the test are checked
+func (c *ResponseBrokerChecker) Prepare(t models.TestInterface) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	for _, check := range t.GetBrokerChecks() {
+		if err := c.broker.Subscribe(ctx, check.Topic); err != nil {
+			return fmt.Errorf("unable to subscribe to topic %s: %w", check.Topic, err)
+		}
+	}
+	return nil
+}
+
+func (c *ResponseBrokerChecker) Check(t models.TestInterface, result *models.Result) ([]error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var errors []error
+	for _, check := range t.GetBrokerChecks() {
+		messages, err := c.broker.Messages(ctx, check.Topic)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read messages of topic %s: %w", check.Topic, err)
+		}
+
+		if len(check.Messages) != len(messages) {
+			errors = append(errors, fmt.Errorf(
+				"quantity of messages in topic %s do not match (-expected: %s +actual: %s)\n    messages diff:\n%s",
+				check.Topic,
+				color.CyanString("%v", len(check.Messages)),
+				color.CyanString("%v", len(messages)),
+				color.CyanString("%v", pretty.Compare(check.Messages, messages)),
+			))
+			continue
+		}
+
+		var withKeys, withHeaders bool
+		expected := make([]interface{}, 0, len(check.Messages))
+		for _, m := range check.Messages {
+			withKeys = withKeys || m.Key != ""
+			withHeaders = withHeaders || len(m.Headers) != 0
+			expected = append(expected, expectedMessage(m))
+		}
+		actual := make([]interface{}, 0, len(messages))
+		for _, m := range messages {
+			actual = append(actual, actualMessage(m, withKeys, withHeaders))
+		}
+
+		for _, err := range compare.Compare(expected, actual, check.ComparisonParams) {
+			errors = append(errors, fmt.Errorf("topic %s: %w", check.Topic, err))
+		}
+	}
+
+	return errors, nil
+}
+
+// expectedMessage makes a value to compare with the actual message, the key and headers are compared if they are given
+func expectedMessage(m models.BrokerMessage) map[string]interface{} {
+	res := map[string]interface{}{
+		"value": decodeValue(m.Value),
+	}
+	if m.Key != "" {
+		res["key"] = m.Key
+	}
+	if len(m.Headers) != 0 {
+		headers := make(map[string]interface{}, len(m.Headers))
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+		res["headers"] = headers
+	}
+	return res
+}
+
+// actualMessage makes a value to compare with the expected message,
+// the key and headers are omitted if no expected message of the topic has them
+func actualMessage(m broker.Message, withKey, withHeaders bool) map[string]interface{} {
+	res := map[string]interface{}{
+		"value": decodeValue(m.Value),
+	}
+	if withKey {
+		res["key"] = m.Key
+	}
+	if withHeaders {
+		headers := make(map[string]interface{}, len(m.Headers))
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+		res["headers"] = headers
+	}
+	return res
+}
+
+// decodeValue decodes the value from JSON, the value which is not valid JSON is compared as text
+func decodeValue(value string) interface{} {
+	var res interface{}
+	if err := json.Unmarshal([]byte(value), &res); err != nil {
+		return value
+	}
+	return res
+}
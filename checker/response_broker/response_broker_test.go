@@ -0,0 +1,55 @@
+package response_broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/broker"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+)
+
+// hangingBroker never returns messages, like a broker waiting for an offset which is never read
+type hangingBroker struct {
+	*broker.InMemory
+}
+
+func (b *hangingBroker) Messages(ctx context.Context, _ string) ([]broker.Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newTest(checks ...models.BrokerCheck) *yaml_file.Test {
+	test := &yaml_file.Test{}
+	test.BrokerChecks = checks
+	return test
+}
+
+func TestCheckShouldCompareMessagesPublishedAfterPrepare(t *testing.T) {
+	b := broker.NewInMemory()
+	require.NoError(t, b.Publish(context.Background(), "orders", []broker.Message{{Value: `{"id": 1}`}}))
+
+	test := newTest(models.BrokerCheck{Topic: "orders", Messages: []models.BrokerMessage{{Value: `{"id": 2}`}}})
+	c := NewChecker(b)
+	require.NoError(t, c.Prepare(test))
+	require.NoError(t, b.Publish(context.Background(), "orders", []broker.Message{{Value: `{"id": 2}`}}))
+
+	errs, err := c.Check(test, &models.Result{})
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
+
+func TestCheckShouldStopWaitingAfterTimeout(t *testing.T) {
+	test := newTest(models.BrokerCheck{Topic: "orders"})
+	c := NewCheckerWithTimeout(&hangingBroker{broker.NewInMemory()}, 50*time.Millisecond)
+
+	started := time.Now()
+	_, err := c.Check(test, &models.Result{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, int64(time.Since(started)), int64(time.Second))
+}
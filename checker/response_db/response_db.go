@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/lamoda/gonkey/checker"
@@ -15,12 +16,21 @@ import (
 )
 
 type ResponseDbChecker struct {
-	db *sql.DB
+	db     *sql.DB
+	dbType string
 }
 
 func NewChecker(dbConnect *sql.DB) checker.CheckerInterface {
+	return NewCheckerForDbType(dbConnect, "postgres")
+}
+
+// NewCheckerForDbType creates a checker which builds query results
+// according to the SQL dialect of the database, dbType is the value of -db-type flag:
+// postgres, mysql or sqlite
+func NewCheckerForDbType(dbConnect *sql.DB, dbType string) checker.CheckerInterface {
 	return &ResponseDbChecker{
-		db: dbConnect,
+		db:     dbConnect,
+		dbType: dbType,
 	}
 }
 
@@ -33,6 +43,10 @@ func (c *ResponseDbChecker) Check(t models.TestInterface, result *models.Result)
 	errors = append(errors, errs...)
 
 	for _, dbCheck := range t.GetDatabaseChecks() {
+		// MongoDB checks are made by response_mongo checker
+		if mongoCheck, ok := dbCheck.(models.MongoDatabaseCheck); ok && mongoCheck.GetMongoQuery() != nil {
+			continue
+		}
 		errs, err := c.check(t.GetName(), t.IgnoreDbOrdering(), dbCheck, result)
 		if err != nil {
 			return nil, err
@@ -67,7 +81,7 @@ func (c *ResponseDbChecker) check(
 	}
 
 	// get DB response
-	actualDbResponse, err := newQuery(t.DbQueryString(), c.db)
+	actualDbResponse, err := c.query(t.DbQueryString())
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +149,15 @@ func compareDbResponseLength(expected, actual []string, query interface{}) error
 	return err
 }
 
+func (c *ResponseDbChecker) query(dbQuery string) ([]string, error) {
+	switch c.dbType {
+	case "mysql", "sqlite":
+		return newRowsQuery(dbQuery, c.db)
+	default:
+		return newQuery(dbQuery, c.db)
+	}
+}
+
 func newQuery(dbQuery string, db *sql.DB) ([]string, error) {
 
 	var dbResponse []string
@@ -164,3 +187,77 @@ func newQuery(dbQuery string, db *sql.DB) ([]string, error) {
 
 	return dbResponse, nil
 }
+
+// newRowsQuery runs the query as is and converts every row to JSON on the client side,
+// for databases without row_to_json
+func newRowsQuery(dbQuery string, db *sql.DB) ([]string, error) {
+	dbQuery = strings.TrimSuffix(strings.TrimSpace(dbQuery), ";")
+
+	rows, err := db.Query(dbQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var dbResponse []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column.Name()] = columnValue(column, values[i])
+		}
+		jsonString, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		dbResponse = append(dbResponse, string(jsonString))
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return dbResponse, nil
+}
+
+// columnValue converts raw value returned by the driver to the value suitable for JSON
+func columnValue(column *sql.ColumnType, value interface{}) interface{} {
+	raw, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	typeName := strings.ToUpper(column.DatabaseTypeName())
+	switch {
+	case strings.Contains(typeName, "INT"):
+		if n, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return n
+		}
+	case strings.Contains(typeName, "DECIMAL"),
+		strings.Contains(typeName, "NUMERIC"),
+		strings.Contains(typeName, "FLOAT"),
+		strings.Contains(typeName, "DOUBLE"),
+		strings.Contains(typeName, "REAL"):
+		if _, err := strconv.ParseFloat(string(raw), 64); err == nil {
+			return json.Number(raw)
+		}
+	case typeName == "JSON":
+		if json.Valid(raw) {
+			return json.RawMessage(raw)
+		}
+	}
+	return string(raw)
+}
@@ -0,0 +1,76 @@
+package response_db
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+)
+
+func openSqlite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, title TEXT, price DECIMAL(10,2), paid BOOLEAN, created_at DATETIME);
+		INSERT INTO orders (title, price, paid, created_at) VALUES ('first', 10.5, 1, '2020-01-02 03:04:05'), ('second', 3, 0, NULL);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestCheckShouldCompareSqliteRows(t *testing.T) {
+	test := &yaml_file.Test{
+		DbQuery: "SELECT id, title, price, paid, created_at FROM orders ORDER BY id;",
+		DbResponse: []string{
+			`{"id": 1, "title": "first", "price": 10.5, "paid": true, "created_at": "2020-01-02T03:04:05Z"}`,
+			`{"id": 2, "title": "second", "price": 3, "paid": false, "created_at": null}`,
+		},
+	}
+
+	checker := NewCheckerForDbType(openSqlite(t), "sqlite")
+	result := &models.Result{}
+	errs, err := checker.Check(test, result)
+
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Len(t, result.DatabaseResult, 1)
+}
+
+func TestCheckShouldReportSqliteMismatch(t *testing.T) {
+	test := &yaml_file.Test{
+		DbQuery: "SELECT title FROM orders WHERE id = 2",
+		DbResponse: []string{
+			`{"title": "first"}`,
+		},
+	}
+
+	checker := NewCheckerForDbType(openSqlite(t), "sqlite")
+	errs, err := checker.Check(test, &models.Result{})
+
+	require.NoError(t, err)
+	assert.Len(t, errs, 1)
+}
+
+func TestCheckShouldKeepSemicolonInSqliteLiteral(t *testing.T) {
+	test := &yaml_file.Test{
+		DbQuery: "SELECT title FROM orders WHERE title != 'a;b' ORDER BY id;",
+		DbResponse: []string{
+			`{"title": "first"}`,
+			`{"title": "second"}`,
+		},
+	}
+
+	checker := NewCheckerForDbType(openSqlite(t), "sqlite")
+	errs, err := checker.Check(test, &models.Result{})
+
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
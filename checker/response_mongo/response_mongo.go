@@ -0,0 +1,169 @@
+package response_mongo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/kylelemons/godebug/pretty"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/lamoda/gonkey/checker"
+	"github.com/lamoda/gonkey/compare"
+	"github.com/lamoda/gonkey/models"
+)
+
+type ResponseMongoChecker struct {
+	db *mongo.Database
+}
+
+func NewChecker(db *mongo.Database) checker.CheckerInterface {
+	return &ResponseMongoChecker{
+		db: db,
+	}
+}
+
+func (c *ResponseMongoChecker) Check(t models.TestInterface, result *models.Result) ([]error, error) {
+	var errors []error
+	for _, dbCheck := range t.GetDatabaseChecks() {
+		mongoCheck, ok := dbCheck.(models.MongoDatabaseCheck)
+		if !ok || mongoCheck.GetMongoQuery() == nil {
+			continue
+		}
+		errs, err := c.check(t.GetName(), t.IgnoreDbOrdering(), mongoCheck, result)
+		if err != nil {
+			return nil, err
+		}
+		errors = append(errors, errs...)
+	}
+
+	return errors, nil
+}
+
+func (c *ResponseMongoChecker) check(
+	testName string,
+	ignoreOrdering bool,
+	t models.MongoDatabaseCheck,
+	result *models.Result,
+) ([]error, error) {
+	query := t.GetMongoQuery()
+
+	if t.DbResponseJson() == nil {
+		return nil, fmt.Errorf("expected DB response not found for test \"%s\"", testName)
+	}
+
+	actualDbResponse, err := c.query(query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query MongoDB for test \"%s\": %w", testName, err)
+	}
+
+	result.DatabaseResult = append(
+		result.DatabaseResult,
+		models.DatabaseResult{Query: queryString(query), Response: actualDbResponse},
+	)
+
+	// compare responses length
+	if len(t.DbResponseJson()) != len(actualDbResponse) {
+		return []error{fmt.Errorf(
+			"quantity of documents in database do not match (-expected: %s +actual: %s)\n     test query:\n%s\n    result diff:\n%s",
+			color.CyanString("%v", len(t.DbResponseJson())),
+			color.CyanString("%v", len(actualDbResponse)),
+			color.CyanString("%v", queryString(query)),
+			color.CyanString("%v", pretty.Compare(t.DbResponseJson(), actualDbResponse)),
+		)}, nil
+	}
+
+	// compare responses as json lists
+	expectedItems, err := toJsonArray(t.DbResponseJson(), "expected", testName)
+	if err != nil {
+		return nil, err
+	}
+	actualItems, err := toJsonArray(actualDbResponse, "actual", testName)
+	if err != nil {
+		return nil, err
+	}
+
+	return compare.Compare(expectedItems, actualItems, compare.CompareParams{
+		IgnoreArraysOrdering: ignoreOrdering,
+	}), nil
+}
+
+// query finds or aggregates documents and returns them in relaxed extended JSON
+func (c *ResponseMongoChecker) query(q *models.MongoQuery) ([]string, error) {
+	ctx := context.Background()
+	collection := c.db.Collection(q.Collection)
+
+	var (
+		cursor *mongo.Cursor
+		err    error
+	)
+	if q.Aggregate != "" {
+		var pipeline bson.A
+		if err := bson.UnmarshalExtJSON([]byte(q.Aggregate), false, &pipeline); err != nil {
+			return nil, fmt.Errorf("invalid aggregate pipeline: %w", err)
+		}
+		cursor, err = collection.Aggregate(ctx, pipeline)
+	} else {
+		filter := bson.D{}
+		if q.Find != "" {
+			if err := bson.UnmarshalExtJSON([]byte(q.Find), false, &filter); err != nil {
+				return nil, fmt.Errorf("invalid find filter: %w", err)
+			}
+		}
+		opts := options.Find()
+		if q.Sort != "" {
+			var sort bson.D
+			if err := bson.UnmarshalExtJSON([]byte(q.Sort), false, &sort); err != nil {
+				return nil, fmt.Errorf("invalid sort: %w", err)
+			}
+			opts.SetSort(sort)
+		}
+		cursor, err = collection.Find(ctx, filter, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []string
+	for cursor.Next(ctx) {
+		doc, err := bson.MarshalExtJSON(cursor.Current, false, false)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, string(doc))
+	}
+	return documents, cursor.Err()
+}
+
+func queryString(q *models.MongoQuery) string {
+	if q.Aggregate != "" {
+		return fmt.Sprintf("db.%s.aggregate(%s)", q.Collection, q.Aggregate)
+	}
+	if q.Sort != "" {
+		return fmt.Sprintf("db.%s.find(%s).sort(%s)", q.Collection, q.Find, q.Sort)
+	}
+	return fmt.Sprintf("db.%s.find(%s)", q.Collection, q.Find)
+}
+
+func toJsonArray(items []string, qual, testName string) ([]interface{}, error) {
+	var itemJSONs []interface{}
+	for i, row := range items {
+		var itemJson interface{}
+		if err := json.Unmarshal([]byte(row), &itemJson); err != nil {
+			return nil, fmt.Errorf(
+				"invalid JSON in the %s DB response for test %s:\n document #%d:\n %s\n error:\n%s",
+				qual,
+				testName,
+				i,
+				row,
+				err.Error(),
+			)
+		}
+		itemJSONs = append(itemJSONs, itemJson)
+	}
+	return itemJSONs, nil
+}
@@ -55,3 +55,9 @@ func CmdRun(scriptPath string, timeout int) error {
 
 	return nil
 }
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
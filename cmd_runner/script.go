@@ -0,0 +1,78 @@
+package cmd_runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Script is a script run as the subject of a test
+type Script struct {
+	Path string
+	Args []string
+	// Env is added to the environment of gonkey
+	Env map[string]string
+	// Timeout in seconds, 3 by default
+	Timeout int
+}
+
+// Output of the finished script
+type Output struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Run runs the script and returns its output. Non-zero exit code is not an error,
+// the error is returned if the script can't be started or doesn't finish in time.
+func Run(script Script) (*Output, error) {
+	timeout := script.Timeout
+	if timeout <= 0 {
+		timeout = 3
+	}
+
+	cmd := exec.Command(strings.TrimRight(script.Path, "\n"), script.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range script.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	setProcessGroup(cmd)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case <-time.After(time.Duration(timeout) * time.Second):
+		if err := killProcess(cmd); err != nil {
+			return nil, err
+		}
+		<-done
+		return nil, fmt.Errorf("process killed as timeout(%d) reached", timeout)
+	case err := <-done:
+		output := &Output{
+			Stdout: stdout.String(),
+			Stderr: stderr.String(),
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			output.ExitCode = exitErr.ExitCode()
+		} else if err != nil {
+			return nil, err
+		}
+		return output, nil
+	}
+}
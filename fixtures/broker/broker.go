@@ -0,0 +1,134 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lamoda/gonkey/broker"
+)
+
+// LoaderBroker publishes messages from fixture files to topics of the message broker
+type LoaderBroker struct {
+	broker   broker.Broker
+	location string
+	debug    bool
+}
+
+type message struct {
+	Key     string            `yaml:"key"`
+	Value   string            `yaml:"value"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type fixture struct {
+	Inherits []string             `yaml:"inherits"`
+	Topics   map[string][]message `yaml:"topics"`
+}
+
+type loadContext struct {
+	files  []string
+	topics []string
+	// messages of the topics in the order of loading
+	messages map[string][]broker.Message
+}
+
+func New(b broker.Broker, location string, debug bool) *LoaderBroker {
+	return &LoaderBroker{
+		broker:   b,
+		location: location,
+		debug:    debug,
+	}
+}
+
+func (l *LoaderBroker) Load(names []string) error {
+	ctx := loadContext{
+		messages: make(map[string][]broker.Message),
+	}
+
+	for _, name := range names {
+		if err := l.loadFile(name, &ctx); err != nil {
+			return fmt.Errorf("unable to load fixture %s: %s", name, err.Error())
+		}
+	}
+
+	for _, topic := range ctx.topics {
+		if l.debug {
+			fmt.Printf("Publishing %d messages to topic %s\n", len(ctx.messages[topic]), topic)
+		}
+		if err := l.broker.Publish(context.Background(), topic, ctx.messages[topic]); err != nil {
+			return fmt.Errorf("unable to publish messages to topic %s: %s", topic, err.Error())
+		}
+	}
+	return nil
+}
+
+func (l *LoaderBroker) loadFile(name string, ctx *loadContext) error {
+	candidates := []string{
+		l.location + "/" + name,
+		l.location + "/" + name + ".yml",
+		l.location + "/" + name + ".yaml",
+	}
+	var err error
+	var file string
+	for _, candidate := range candidates {
+		if _, err = os.Stat(candidate); err == nil {
+			file = candidate
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// skip previously loaded files
+	for _, f := range ctx.files {
+		if f == file {
+			return nil
+		}
+	}
+	if l.debug {
+		fmt.Println("Loading", file)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	ctx.files = append(ctx.files, file)
+
+	var loadedFixture fixture
+	if err := yaml.UnmarshalStrict(data, &loadedFixture); err != nil {
+		return err
+	}
+
+	// messages of inherited fixtures are published first
+	for _, inheritFile := range loadedFixture.Inherits {
+		if err := l.loadFile(inheritFile, ctx); err != nil {
+			return err
+		}
+	}
+
+	topics := make([]string, 0, len(loadedFixture.Topics))
+	for topic := range loadedFixture.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	for _, topic := range topics {
+		if _, ok := ctx.messages[topic]; !ok {
+			ctx.topics = append(ctx.topics, topic)
+			ctx.messages[topic] = nil
+		}
+		for _, m := range loadedFixture.Topics[topic] {
+			ctx.messages[topic] = append(ctx.messages[topic], broker.Message{
+				Key:     m.Key,
+				Value:   m.Value,
+				Headers: m.Headers,
+			})
+		}
+	}
+	return nil
+}
@@ -7,9 +7,12 @@ import (
 	_ "github.com/lib/pq"
 
 	"github.com/lamoda/gonkey/fixtures/aerospike"
+	"github.com/lamoda/gonkey/fixtures/mongo"
 	"github.com/lamoda/gonkey/fixtures/mysql"
 	"github.com/lamoda/gonkey/fixtures/postgres"
+	"github.com/lamoda/gonkey/fixtures/sqlite"
 	aerospikeClient "github.com/lamoda/gonkey/storage/aerospike"
+	mongoClient "github.com/lamoda/gonkey/storage/mongo"
 )
 
 type DbType int
@@ -20,6 +23,8 @@ const (
 	Aerospike
 	Redis
 	CustomLoader // using external loader if gonkey used as a library
+	Mongo
+	Sqlite
 )
 
 const (
@@ -27,11 +32,14 @@ const (
 	MysqlParam     = "mysql"
 	AerospikeParam = "aerospike"
 	RedisParam     = "redis"
+	MongoParam     = "mongo"
+	SqliteParam    = "sqlite"
 )
 
 type Config struct {
 	DB            *sql.DB
 	Aerospike     *aerospikeClient.Client
+	Mongo         *mongoClient.Client
 	DbType        DbType
 	Location      string
 	Debug         bool
@@ -67,6 +75,18 @@ func NewLoader(cfg *Config) Loader {
 			location,
 			cfg.Debug,
 		)
+	case Sqlite:
+		loader = sqlite.New(
+			cfg.DB,
+			location,
+			cfg.Debug,
+		)
+	case Mongo:
+		loader = mongo.New(
+			cfg.Mongo,
+			location,
+			cfg.Debug,
+		)
 	default:
 		if cfg.FixtureLoader != nil {
 			return cfg.FixtureLoader
@@ -87,6 +107,10 @@ func FetchDbType(dbType string) DbType {
 		return Aerospike
 	case RedisParam:
 		return Redis
+	case MongoParam:
+		return Mongo
+	case SqliteParam:
+		return Sqlite
 	default:
 		panic("unknown db type param")
 	}
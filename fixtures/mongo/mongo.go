@@ -0,0 +1,409 @@
+package mongo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v2"
+)
+
+type mongoClient interface {
+	Truncate(collection string) error
+	InsertDocuments(collection string, documents []interface{}) ([]interface{}, error)
+}
+
+type LoaderMongo struct {
+	client   mongoClient
+	location string
+	debug    bool
+}
+
+type document map[string]interface{}
+
+type collection []document
+
+type documentsDict map[string]document
+
+type fixture struct {
+	Inherits    []string
+	Collections yaml.MapSlice
+	Templates   yaml.MapSlice
+}
+
+type loadedCollection struct {
+	name      string
+	documents collection
+}
+
+type loadContext struct {
+	files          []string
+	collections    []loadedCollection
+	refsDefinition documentsDict
+	refsInserted   documentsDict
+}
+
+var (
+	evalRx     = regexp.MustCompile(`^\$eval\((.+)\)$`)
+	objectIDRx = regexp.MustCompile(`^ObjectId\(\s*(?:"([0-9a-fA-F]{24})")?\s*\)$`)
+	isoDateRx  = regexp.MustCompile(`^ISODate\(\s*"(.+)"\s*\)$`)
+	newDateRx  = regexp.MustCompile(`^new Date\(\s*\)$`)
+)
+
+func New(client mongoClient, location string, debug bool) *LoaderMongo {
+	return &LoaderMongo{
+		client:   client,
+		location: location,
+		debug:    debug,
+	}
+}
+
+func (f *LoaderMongo) Load(names []string) error {
+	ctx := loadContext{
+		refsDefinition: make(documentsDict),
+		refsInserted:   make(documentsDict),
+	}
+	// gather data from files
+	for _, name := range names {
+		err := f.loadFile(name, &ctx)
+		if err != nil {
+			return fmt.Errorf("unable to load fixture %s: %s", name, err.Error())
+		}
+	}
+	return f.loadCollections(&ctx)
+}
+
+func (f *LoaderMongo) loadFile(name string, ctx *loadContext) error {
+	candidates := []string{
+		f.location + "/" + name,
+		f.location + "/" + name + ".yml",
+		f.location + "/" + name + ".yaml",
+	}
+	var err error
+	var file string
+	for _, candidate := range candidates {
+		if _, err = os.Stat(candidate); err == nil {
+			file = candidate
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// skip previously loaded files
+	if inArray(file, ctx.files) {
+		return nil
+	}
+	if f.debug {
+		fmt.Println("Loading", file)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	ctx.files = append(ctx.files, file)
+	return f.loadYml(data, ctx)
+}
+
+func (f *LoaderMongo) loadYml(data []byte, ctx *loadContext) error {
+	// read yml into struct
+	var loadedFixture fixture
+	if err := yaml.Unmarshal(data, &loadedFixture); err != nil {
+		return err
+	}
+
+	// load inherits
+	for _, inheritFile := range loadedFixture.Inherits {
+		if err := f.loadFile(inheritFile, ctx); err != nil {
+			return err
+		}
+	}
+
+	// loadedFixture.templates
+	// yaml.MapSlice{
+	//    string => yaml.MapSlice{
+	//        string => interface{}
+	//    }
+	// }
+	for _, template := range loadedFixture.Templates {
+		name := template.Key.(string)
+		if _, ok := ctx.refsDefinition[name]; ok {
+			return fmt.Errorf("unable to load template %s: duplicating ref name", name)
+		}
+		fields, ok := template.Value.(yaml.MapSlice)
+		if !ok {
+			return fmt.Errorf("unable to load template %s: expected map", name)
+		}
+		doc := newDocument(fields)
+		if base, ok := doc["$extend"]; ok {
+			baseDoc, err := f.resolveReference(ctx.refsDefinition, base.(string))
+			if err != nil {
+				return err
+			}
+			for k, v := range doc {
+				baseDoc[k] = v
+			}
+			doc = baseDoc
+		}
+		ctx.refsDefinition[name] = doc
+		if f.debug {
+			fmt.Printf("Populating ref %s as %s from template\n", name, toJSON(doc))
+		}
+	}
+
+	// loadedFixture.collections
+	// yaml.MapSlice{
+	//    string => []interface{
+	//        yaml.MapSlice{
+	//            string => interface{}
+	//        }
+	//    }
+	// }
+	for _, sourceCollection := range loadedFixture.Collections {
+		sourceDocuments, ok := sourceCollection.Value.([]interface{})
+		if !ok {
+			return errors.New("expected array at root level")
+		}
+		documents := make(collection, len(sourceDocuments))
+		for i := range sourceDocuments {
+			fields, ok := sourceDocuments[i].(yaml.MapSlice)
+			if !ok {
+				return fmt.Errorf("document #%d of collection %s is not a map", i, sourceCollection.Key)
+			}
+			documents[i] = newDocument(fields)
+		}
+		ctx.collections = append(ctx.collections, loadedCollection{
+			name:      sourceCollection.Key.(string),
+			documents: documents,
+		})
+	}
+	return nil
+}
+
+func (f *LoaderMongo) loadCollections(ctx *loadContext) error {
+	// truncate first
+	truncated := make(map[string]bool)
+	for _, lc := range ctx.collections {
+		if truncated[lc.name] {
+			continue
+		}
+		if f.debug {
+			fmt.Println("Truncating collection", lc.name)
+		}
+		if err := f.client.Truncate(lc.name); err != nil {
+			return err
+		}
+		truncated[lc.name] = true
+	}
+	// then load data
+	for _, lc := range ctx.collections {
+		if len(lc.documents) == 0 {
+			continue
+		}
+		if err := f.loadCollection(ctx, lc); err != nil {
+			return fmt.Errorf("failed to load collection '%s' because:\n%s", lc.name, err)
+		}
+	}
+	return nil
+}
+
+func (f *LoaderMongo) loadCollection(ctx *loadContext, lc loadedCollection) error {
+	// $extend keyword allows to import values from a named document
+	for i, doc := range lc.documents {
+		if base, ok := doc["$extend"]; ok {
+			baseDoc, err := f.resolveReference(ctx.refsDefinition, base.(string))
+			if err != nil {
+				return err
+			}
+			for k, v := range doc {
+				baseDoc[k] = v
+			}
+			lc.documents[i] = baseDoc
+		}
+	}
+
+	documents := make([]interface{}, len(lc.documents))
+	resolved := make([]document, len(lc.documents))
+	for i, doc := range lc.documents {
+		resolved[i] = make(document, len(doc))
+		for k, v := range doc {
+			if len(k) > 0 && k[0] == '$' {
+				continue
+			}
+			value, err := f.resolveValue(v, ctx)
+			if err != nil {
+				return fmt.Errorf("unable to process %s value (document %d): %s", k, i, err.Error())
+			}
+			resolved[i][k] = value
+		}
+		documents[i] = bson.M(resolved[i])
+	}
+	if f.debug {
+		fmt.Printf("Inserting %d documents into %s\n", len(documents), lc.name)
+	}
+
+	ids, err := f.client.InsertDocuments(lc.name, documents)
+	if err != nil {
+		return err
+	}
+
+	for i, doc := range lc.documents {
+		name, ok := doc["$name"]
+		if !ok {
+			continue
+		}
+		refName := name.(string)
+		if _, ok := ctx.refsInserted[refName]; ok {
+			return fmt.Errorf("duplicating ref name %s", refName)
+		}
+		if i < len(ids) {
+			resolved[i]["_id"] = ids[i]
+		}
+		ctx.refsDefinition[refName] = doc
+		ctx.refsInserted[refName] = resolved[i]
+		if f.debug {
+			fmt.Printf("Populating ref %s as %s from inserted values\n", refName, toJSON(resolved[i]))
+		}
+	}
+	return nil
+}
+
+// resolveValue converts YAML value into a value stored in MongoDB,
+// nested maps keep the order of fields, strings starting with dollar sign are resolved as expressions
+func (f *LoaderMongo) resolveValue(value interface{}, ctx *loadContext) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if len(v) > 0 && v[0] == '$' {
+			return f.resolveExpression(v, ctx)
+		}
+		return v, nil
+	case yaml.MapSlice:
+		res := make(bson.D, 0, len(v))
+		for _, item := range v {
+			resolved, err := f.resolveValue(item.Value, ctx)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, bson.E{Key: fmt.Sprint(item.Key), Value: resolved})
+		}
+		return res, nil
+	case []interface{}:
+		res := make(bson.A, len(v))
+		for i, item := range v {
+			resolved, err := f.resolveValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = resolved
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
+
+// resolveExpression converts expressions starting with dollar sign into a value
+// supporting expressions:
+// - $eval()               - evaluates a mongo shell expression, e.g. $eval(ObjectId())
+// - $recordName.fieldName - using value of previously inserted named document
+// supported mongo shell expressions are ObjectId(), ObjectId("<hex>"), ISODate("<RFC 3339 date>") and new Date()
+func (f *LoaderMongo) resolveExpression(expr string, ctx *loadContext) (interface{}, error) {
+	if strings.HasPrefix(expr, "$eval") {
+		matches := evalRx.FindStringSubmatch(expr)
+		if matches == nil {
+			return nil, fmt.Errorf("incorrect $eval() usage: %s", expr)
+		}
+		return evaluate(strings.TrimSpace(matches[1]))
+	}
+	return f.resolveFieldReference(ctx.refsInserted, expr)
+}
+
+func evaluate(expr string) (interface{}, error) {
+	if matches := objectIDRx.FindStringSubmatch(expr); matches != nil {
+		if matches[1] == "" {
+			return primitive.NewObjectID(), nil
+		}
+		return primitive.ObjectIDFromHex(matches[1])
+	}
+	if matches := isoDateRx.FindStringSubmatch(expr); matches != nil {
+		t, err := time.Parse(time.RFC3339Nano, matches[1])
+		if err != nil {
+			return nil, err
+		}
+		return primitive.NewDateTimeFromTime(t), nil
+	}
+	if newDateRx.MatchString(expr) {
+		return primitive.NewDateTimeFromTime(time.Now()), nil
+	}
+	return nil, fmt.Errorf("unsupported $eval() expression: %s", expr)
+}
+
+// resolveReference finds previously stored reference by its name
+func (f *LoaderMongo) resolveReference(refs documentsDict, refName string) (document, error) {
+	target, ok := refs[refName]
+	if !ok {
+		return nil, fmt.Errorf("undefined reference %s", refName)
+	}
+	// make a copy of referencing data to prevent spoiling the source
+	// by the way removing $-records from base document
+	targetCopy := make(document, len(target))
+	for k, v := range target {
+		if len(k) == 0 || k[0] != '$' {
+			targetCopy[k] = v
+		}
+	}
+	return targetCopy, nil
+}
+
+// resolveFieldReference finds previously stored reference by name
+// and return value of its field
+func (f *LoaderMongo) resolveFieldReference(refs documentsDict, ref string) (interface{}, error) {
+	parts := strings.SplitN(ref, ".", 2)
+	if len(parts) < 2 || len(parts[0]) < 2 || len(parts[1]) < 1 {
+		return nil, fmt.Errorf("invalid reference %s, correct form is $refName.field", ref)
+	}
+	// remove leading $
+	refName := parts[0][1:]
+	target, ok := refs[refName]
+	if !ok {
+		return nil, fmt.Errorf("undefined reference %s", refName)
+	}
+	value, ok := target[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("undefined reference field %s", parts[1])
+	}
+	return value, nil
+}
+
+func newDocument(fields yaml.MapSlice) document {
+	doc := make(document, len(fields))
+	for _, field := range fields {
+		doc[fmt.Sprint(field.Key)] = field.Value
+	}
+	return doc
+}
+
+func toJSON(doc document) string {
+	data, err := bson.MarshalExtJSON(bson.M(doc), false, false)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(doc))
+	}
+	return string(data)
+}
+
+// inArray checks whether the needle is present in haystack slice
+func inArray(needle string, haystack []string) bool {
+	for _, e := range haystack {
+		if needle == e {
+			return true
+		}
+	}
+	return false
+}
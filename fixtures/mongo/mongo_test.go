@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeClient struct {
+	truncated []string
+	inserted  map[string][]interface{}
+}
+
+func (c *fakeClient) Truncate(collection string) error {
+	c.truncated = append(c.truncated, collection)
+	return nil
+}
+
+func (c *fakeClient) InsertDocuments(collection string, documents []interface{}) ([]interface{}, error) {
+	c.inserted[collection] = append(c.inserted[collection], documents...)
+	ids := make([]interface{}, len(documents))
+	for i, doc := range documents {
+		ids[i] = doc.(bson.M)["_id"]
+	}
+	return ids, nil
+}
+
+func TestLoaderMongo_Load(t *testing.T) {
+	client := &fakeClient{inserted: make(map[string][]interface{})}
+
+	err := New(client, "../testdata", false).Load([]string{"mongo"})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"orders", "users"}, client.truncated)
+
+	adminID, _ := primitive.ObjectIDFromHex("5f1b0a7e9d3b2a1c4e8f0a11")
+	require.Equal(t, []interface{}{
+		bson.M{
+			"_id":       adminID,
+			"name":      "admin",
+			"role":      "admin",
+			"active":    true,
+			"createdAt": primitive.NewDateTimeFromTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)),
+		},
+	}, client.inserted["users"])
+
+	require.Equal(t, []interface{}{
+		bson.M{"_id": 1, "userId": nil},
+		bson.M{
+			"userId": adminID,
+			"items":  bson.A{bson.D{{Key: "sku", Value: "a1"}, {Key: "qty", Value: 2}}},
+			"address": bson.D{
+				{Key: "city", Value: "Moscow"},
+				{Key: "zip", Value: "101000"},
+			},
+		},
+	}, client.inserted["orders"])
+}
+
+func TestLoaderMongo_resolveExpression(t *testing.T) {
+	l := New(nil, "", false)
+	ctx := &loadContext{refsInserted: documentsDict{"ref": {"field": 42}}}
+
+	value, err := l.resolveExpression("$eval(ObjectId())", ctx)
+	require.NoError(t, err)
+	require.IsType(t, primitive.ObjectID{}, value)
+
+	value, err = l.resolveExpression("$eval(new Date())", ctx)
+	require.NoError(t, err)
+	require.IsType(t, primitive.DateTime(0), value)
+
+	value, err = l.resolveExpression("$ref.field", ctx)
+	require.NoError(t, err)
+	require.Equal(t, 42, value)
+
+	_, err = l.resolveExpression("$eval(db.users.count())", ctx)
+	require.Error(t, err)
+
+	_, err = l.resolveExpression("$ref.unknown", ctx)
+	require.Error(t, err)
+}
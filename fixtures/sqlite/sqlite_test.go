@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInsertQuery(t *testing.T) {
+	l := New(nil, "", false)
+	ctx := &loadContext{refsInserted: rowsDict{"ref": {"id": int64(7)}}}
+
+	query, err := l.buildInsertQuery(ctx, "table", row{
+		"$name":  "name",
+		"field1": `it's "quoted" \ text`,
+		"field2": true,
+		"field3": "$ref.id",
+		"field4": "$eval(CURRENT_DATE)",
+		"field5": []interface{}{1, "2"},
+	})
+	require.NoError(t, err)
+	require.Equal(t,
+		`INSERT INTO "table" ("field1", "field2", "field3", "field4", "field5") `+
+			`VALUES ('it''s "quoted" \ text', 1, 7, (CURRENT_DATE), '[1,"2"]') RETURNING *`,
+		query,
+	)
+}
+
+func TestLoadTablesShouldResolveRefs(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE table1 (id INTEGER PRIMARY KEY AUTOINCREMENT, f1 TEXT, f2 TEXT)`,
+		`CREATE TABLE table2 (id INTEGER PRIMARY KEY AUTOINCREMENT, f1 TEXT, f2 TEXT)`,
+		`CREATE TABLE table3 (f1 TEXT, f2 TEXT)`,
+		`INSERT INTO table1 (f1, f2) VALUES ('old', 'old')`,
+	)
+
+	l := New(db, "../testdata", false)
+	require.NoError(t, l.Load([]string{"sql_refs"}))
+	// the second load checks that tables are truncated and sequences are reset
+	require.NoError(t, l.Load([]string{"sql_refs"}))
+
+	require.Equal(t, [][]string{{"1", "value1", "value2"}}, queryRows(t, db, `SELECT id, f1, f2 FROM table1`))
+	require.Equal(t, [][]string{{"1", "value2", "value1"}}, queryRows(t, db, `SELECT id, f1, f2 FROM table2`))
+	require.Equal(t, [][]string{{"value1", "value2"}}, queryRows(t, db, `SELECT f1, f2 FROM table3`))
+}
+
+func TestLoadTablesShouldExtendRows(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE table1 (f1 TEXT, f2 TEXT, f3 TEXT)`,
+		`CREATE TABLE table2 (f1 TEXT, f2 TEXT, f3 TEXT)`,
+		`CREATE TABLE table3 (f1 TEXT, f2 TEXT, f3 TEXT)`,
+	)
+
+	require.NoError(t, New(db, "../testdata", false).Load([]string{"sql_extend"}))
+
+	require.Equal(t,
+		[][]string{{"value1 overwritten", "value2", "128"}},
+		queryRows(t, db, `SELECT f1, f2, f3 FROM table2`),
+	)
+	require.Equal(t,
+		[][]string{{"value1 overwritten", "value2", "128"}, {"tplVal1", "tplVal2", ""}},
+		queryRows(t, db, `SELECT f1, f2, COALESCE(f3, '') FROM table3 ORDER BY rowid`),
+	)
+}
+
+func openTestDB(t *testing.T, queries ...string) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// every connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, q := range queries {
+		_, err := db.Exec(q)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func queryRows(t *testing.T, db *sql.DB, query string) [][]string {
+	rows, err := db.Query(query)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	require.NoError(t, err)
+
+	var res [][]string
+	for rows.Next() {
+		values := make([]string, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(dest...))
+		res = append(res, values)
+	}
+	require.NoError(t, rows.Err())
+	return res
+}
@@ -6,22 +6,28 @@ require (
 	github.com/Masterminds/sprig/v3 v3.2.2
 	github.com/aerospike/aerospike-client-go/v5 v5.8.0
 	github.com/fatih/color v1.7.0
-	github.com/go-redis/redis/v9 v9.0.0-beta.2 // indirect
-	github.com/google/uuid v1.1.1
+	github.com/go-redis/redis/v9 v9.0.0-beta.2
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/google/go-cmp v0.5.8
+	github.com/google/uuid v1.1.2
 	github.com/huandu/xstrings v1.3.2 // indirect
 	github.com/imdario/mergo v0.3.13 // indirect
+	github.com/jhump/protoreflect v1.14.1
 	github.com/joho/godotenv v1.3.0
 	github.com/kylelemons/godebug v1.1.0
 	github.com/lib/pq v1.3.0
 	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/mitchellh/copystructure v1.2.0 // indirect
-	github.com/onsi/ginkgo v1.16.5 // indirect
-	github.com/stretchr/testify v1.7.1
+	github.com/segmentio/kafka-go v0.4.47
+	github.com/stretchr/testify v1.8.0
 	github.com/tidwall/gjson v1.13.0
 	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
-	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e // indirect
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	go.mongodb.org/mongo-driver v1.11.9
+	golang.org/x/sync v0.1.0
+	google.golang.org/grpc v1.48.0
+	google.golang.org/protobuf v1.28.1
 	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0
 	gopkg.in/yaml.v2 v2.4.0
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
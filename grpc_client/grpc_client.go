@@ -0,0 +1,233 @@
+package grpc_client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Config describes where the descriptors of the called services are taken from
+type Config struct {
+	// ProtoFiles are .proto files which are parsed on start
+	ProtoFiles []string
+	// ImportPaths are used to resolve imports of ProtoFiles
+	ImportPaths []string
+	// Protosets are files with serialized google.protobuf.FileDescriptorSet,
+	// e.g. generated by `protoc --descriptor_set_out --include_imports`
+	Protosets []string
+}
+
+type Client struct {
+	files *protoregistry.Files
+
+	sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// Response of the unary call, the message is converted to JSON
+type Response struct {
+	Status  *status.Status
+	Message string
+	Headers map[string][]string
+}
+
+func New(cfg Config) (*Client, error) {
+	fileSet := &descriptorpb.FileDescriptorSet{}
+
+	if len(cfg.ProtoFiles) != 0 {
+		parser := protoparse.Parser{
+			ImportPaths:      cfg.ImportPaths,
+			InferImportPaths: len(cfg.ImportPaths) == 0,
+		}
+		fds, err := parser.ParseFiles(cfg.ProtoFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse proto files: %w", err)
+		}
+		seen := make(map[string]bool)
+		for _, fd := range fds {
+			addWithDependencies(fileSet, fd, seen)
+		}
+	}
+
+	for _, name := range cfg.Protosets {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("unable to parse protoset %s: %w", name, err)
+		}
+		fileSet.File = append(fileSet.File, set.File...)
+	}
+
+	files, err := protodesc.NewFiles(dedupFiles(fileSet))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load descriptors: %w", err)
+	}
+
+	return &Client{
+		files: files,
+		conns: make(map[string]*grpc.ClientConn),
+	}, nil
+}
+
+func addWithDependencies(set *descriptorpb.FileDescriptorSet, fd *desc.FileDescriptor, seen map[string]bool) {
+	if seen[fd.GetName()] {
+		return
+	}
+	seen[fd.GetName()] = true
+	for _, dep := range fd.GetDependencies() {
+		addWithDependencies(set, dep, seen)
+	}
+	set.File = append(set.File, fd.AsFileDescriptorProto())
+}
+
+func dedupFiles(set *descriptorpb.FileDescriptorSet) *descriptorpb.FileDescriptorSet {
+	res := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	for _, f := range set.File {
+		if seen[f.GetName()] {
+			continue
+		}
+		seen[f.GetName()] = true
+		res.File = append(res.File, f)
+	}
+	return res
+}
+
+// FindMethod looks up the method of the service by their full names, e.g. `helloworld.Greeter` and `SayHello`
+func (c *Client) FindMethod(service, method string) (protoreflect.MethodDescriptor, error) {
+	d, err := c.files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", method, service)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s.%s is streaming, only unary methods are supported", service, method)
+	}
+	return md, nil
+}
+
+// Invoke makes the unary call of the method with the message given in JSON
+func (c *Client) Invoke(
+	ctx context.Context,
+	address, service, method, message string,
+	md map[string]string,
+) (*Response, error) {
+	methodDesc, err := c.FindMethod(service, method)
+	if err != nil {
+		return nil, err
+	}
+
+	req := dynamicpb.NewMessage(methodDesc.Input())
+	if strings.TrimSpace(message) != "" {
+		if err := protojson.Unmarshal([]byte(message), req); err != nil {
+			return nil, fmt.Errorf("unable to convert message to %s: %w", methodDesc.Input().FullName(), err)
+		}
+	}
+
+	conn, err := c.conn(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(md) != 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(md))
+	}
+
+	var header metadata.MD
+	reply := dynamicpb.NewMessage(methodDesc.Output())
+	fullMethod := fmt.Sprintf("/%s/%s", methodDesc.Parent().FullName(), methodDesc.Name())
+	err = conn.Invoke(ctx, fullMethod, req, reply, grpc.Header(&header))
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, err
+	}
+
+	res := &Response{
+		Status:  st,
+		Headers: make(map[string][]string, len(header)),
+	}
+	for k, v := range header {
+		res.Headers[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	if err != nil {
+		data, err := protojson.Marshal(st.Proto())
+		if err != nil {
+			return nil, err
+		}
+		res.Message = string(data)
+		return res, nil
+	}
+
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(reply)
+	if err != nil {
+		return nil, err
+	}
+	res.Message = string(data)
+
+	return res, nil
+}
+
+func (c *Client) conn(address string) (*grpc.ClientConn, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if conn, ok := c.conns[address]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c.conns[address] = conn
+
+	return conn, nil
+}
+
+// Close closes all connections opened by the client
+func (c *Client) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	var errs []string
+	for address, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", address, err))
+		}
+	}
+	c.conns = make(map[string]*grpc.ClientConn)
+
+	if len(errs) != 0 {
+		return fmt.Errorf("unable to close connections: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -1,26 +1,47 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/aerospike/aerospike-client-go/v5"
 	"github.com/go-redis/redis/v9"
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
+	_ "github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 
+	"github.com/lamoda/gonkey/broker"
+	"github.com/lamoda/gonkey/broker/kafka"
 	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/checker/response_broker"
 	"github.com/lamoda/gonkey/checker/response_db"
+	"github.com/lamoda/gonkey/checker/response_mongo"
 	"github.com/lamoda/gonkey/fixtures"
+	brokerFixtures "github.com/lamoda/gonkey/fixtures/broker"
 	redisLoader "github.com/lamoda/gonkey/fixtures/redis"
+	"github.com/lamoda/gonkey/grpc_client"
+	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/models"
 	"github.com/lamoda/gonkey/output/allure_report"
 	"github.com/lamoda/gonkey/output/console_colored"
+	"github.com/lamoda/gonkey/output/json_report"
+	"github.com/lamoda/gonkey/output/junit_report"
 	"github.com/lamoda/gonkey/runner"
 	aerospikeAdapter "github.com/lamoda/gonkey/storage/aerospike"
+	mongoAdapter "github.com/lamoda/gonkey/storage/mongo"
 	"github.com/lamoda/gonkey/testloader/yaml_file"
 	"github.com/lamoda/gonkey/variables"
 )
@@ -37,14 +58,48 @@ type config struct {
 	Verbose          bool
 	Debug            bool
 	DbType           string
+	Parallel         int
+	GrpcHost         string
+	ProtoFiles       string
+	ProtoImportPaths string
+	Protosets        string
+	KafkaBrokers     string
+	BrokerTimeout    time.Duration
+	JUnitReport      string
+	JSONReport       string
+	JSONReportFormat string
+	Load             bool
+	LoadDuration     time.Duration
+	LoadRPS          int
+	LoadConcurrency  int
+	Tags             string
+	ExcludeTags      string
+	Run              string
+	Files            string
+	HooksFile        string
+	MocksConfig      string
+	MocksDir         string
+	Watch            bool
+	Update           bool
 }
 
 type storages struct {
 	db        *sql.DB
 	aerospike *aerospikeAdapter.Client
+	mongo     *mongo.Database
+	broker    broker.Broker
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		record(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mock-server" {
+		mockServer(os.Args[2:])
+		return
+	}
+
 	cfg := getConfig()
 	validateConfig(&cfg)
 
@@ -52,9 +107,19 @@ func main() {
 
 	fixturesLoader := initLoaders(storages, cfg)
 
-	runnerInstance := initRunner(cfg, fixturesLoader)
+	runnerInstance := initRunner(cfg, fixturesLoader, storages)
+
+	addCheckers(runnerInstance, storages, cfg)
 
-	addCheckers(runnerInstance, storages.db)
+	if cfg.Load {
+		runLoad(runnerInstance, cfg)
+		return
+	}
+
+	if cfg.Watch {
+		runWatch(runnerInstance, cfg)
+		return
+	}
 
 	run(runnerInstance, cfg)
 }
@@ -65,16 +130,19 @@ func initStorages(cfg config) storages {
 	return storages{
 		db:        db,
 		aerospike: aerospikeClient,
+		mongo:     initMongo(cfg),
+		broker:    initBroker(cfg),
 	}
 }
 
 func initLoaders(storages storages, cfg config) fixtures.Loader {
 	var fixturesLoader fixtures.Loader
 	if cfg.FixturesLocation != "" {
-		if storages.db != nil || storages.aerospike != nil {
+		if storages.db != nil || storages.aerospike != nil || storages.mongo != nil {
 			fixturesLoader = fixtures.NewLoader(&fixtures.Config{
 				DB:        storages.db,
 				Aerospike: storages.aerospike,
+				Mongo:     mongoAdapter.New(storages.mongo),
 				Location:  cfg.FixturesLocation,
 				Debug:     cfg.Debug,
 				DbType:    fixtures.FetchDbType(cfg.DbType),
@@ -86,7 +154,7 @@ func initLoaders(storages storages, cfg config) fixtures.Loader {
 					Addr: cfg.RedisAddr,
 				},
 			})
-		} else {
+		} else if storages.broker == nil {
 			log.Fatal(errors.New("you should specify db_dsn to load fixtures"))
 		}
 	}
@@ -114,10 +182,16 @@ func validateConfig(cfg *config) {
 	}
 }
 
-func addCheckers(r *runner.Runner, db *sql.DB) {
+func addCheckers(r *runner.Runner, storages storages, cfg config) {
 	r.AddCheckers(response_body.NewChecker())
-	if db != nil {
-		r.AddCheckers(response_db.NewChecker(db))
+	if storages.db != nil {
+		r.AddCheckers(response_db.NewCheckerForDbType(storages.db, cfg.DbType))
+	}
+	if storages.mongo != nil {
+		r.AddCheckers(response_mongo.NewChecker(storages.mongo))
+	}
+	if storages.broker != nil {
+		r.AddCheckers(response_broker.NewCheckerWithTimeout(storages.broker, cfg.BrokerTimeout))
 	}
 }
 
@@ -131,6 +205,27 @@ func run(r *runner.Runner, cfg config) {
 		r.AddOutput(allureOutput)
 	}
 
+	var junitOutput *junit_report.JUnitReportOutput
+	if cfg.JUnitReport != "" {
+		junitOutput = junit_report.NewOutput("Gonkey", cfg.JUnitReport)
+		r.AddOutput(junitOutput)
+	}
+
+	var (
+		jsonOutput *json_report.JSONReportOutput
+		jsonFile   *os.File
+	)
+	if cfg.JSONReport != "" {
+		jsonOutput, jsonFile = initJSONReport(cfg)
+		r.AddOutput(jsonOutput)
+	}
+
+	var updater *yaml_file.Updater
+	if cfg.Update {
+		updater = yaml_file.NewUpdater()
+		r.AddOutput(updater)
+	}
+
 	summary, err := r.Run()
 	if err != nil {
 		log.Fatal(err)
@@ -138,26 +233,209 @@ func run(r *runner.Runner, cfg config) {
 
 	consoleOutput.ShowSummary(summary)
 
+	if updater != nil {
+		updateTests(updater)
+	}
+
 	if allureOutput != nil {
 		allureOutput.Finalize()
 	}
 
+	if junitOutput != nil {
+		if err := junitOutput.Finalize(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if jsonOutput != nil {
+		if err := jsonOutput.Finalize(summary); err != nil {
+			log.Fatal(err)
+		}
+		if err := jsonFile.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	if !summary.Success {
 		os.Exit(1)
 	}
 }
 
-func initRunner(cfg config, fixturesLoader fixtures.Loader) *runner.Runner {
+func updateTests(updater *yaml_file.Updater) {
+	updated, skipped, err := updater.Save()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range updated {
+		log.Printf("updated expected values of %s", name)
+	}
+	for _, reason := range skipped {
+		log.Printf("not updated %s", reason)
+	}
+}
+
+func runLoad(r *runner.Runner, cfg config) {
+	summary, err := r.RunLoad(runner.LoadConfig{
+		Duration:    cfg.LoadDuration,
+		RPS:         cfg.LoadRPS,
+		Concurrency: cfg.LoadConcurrency,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	console_colored.NewOutput(cfg.Verbose).ShowLoadSummary(summary)
+}
+
+// runWatch re-runs the tests on changes of the files until the process is interrupted,
+// only the console output is used as the reports of separate runs would overwrite each other
+func runWatch(r *runner.Runner, cfg config) {
+	consoleOutput := console_colored.NewOutput(cfg.Verbose)
+	r.AddOutput(consoleOutput)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	err := r.Watch(ctx, runner.WatchConfig{
+		TestsLocation:    cfg.TestsLocation,
+		FixturesLocation: cfg.FixturesLocation,
+		MocksLocation:    cfg.MocksDir,
+	}, consoleOutput.ShowSummary)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// initJSONReport creates the JSON report output and its file, the file is closed by the caller after the run
+func initJSONReport(cfg config) (*json_report.JSONReportOutput, *os.File) {
+	var format json_report.Format
+	switch cfg.JSONReportFormat {
+	case "ndjson":
+		format = json_report.NDJSON
+	case "json":
+		format = json_report.JSON
+	default:
+		log.Fatalf("unknown JSON report format %s, should be ndjson or json", cfg.JSONReportFormat)
+	}
+
+	f, err := os.Create(cfg.JSONReport)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return json_report.NewOutput(f, format), f
+}
+
+func initRunner(cfg config, fixturesLoader fixtures.Loader, storages storages) *runner.Runner {
+	var brokerFixturesLoader fixtures.Loader
+	if storages.broker != nil && cfg.FixturesLocation != "" {
+		brokerFixturesLoader = brokerFixtures.New(storages.broker, cfg.FixturesLocation, cfg.Debug)
+	}
+
+	var hooks *models.Hooks
+	if cfg.HooksFile != "" {
+		var err error
+		if hooks, err = yaml_file.ParseHooksFile(cfg.HooksFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	m, vars := initMocks(cfg)
+
+	var mocksLoader *mocks.Loader
+	if m != nil {
+		mocksLoader = mocks.NewLoader(m)
+	}
+
 	return runner.New(
 		&runner.Config{
-			Host:           cfg.Host,
-			FixturesLoader: fixturesLoader,
-			Variables:      variables.New(),
+			Host:                 cfg.Host,
+			FixturesLoader:       fixturesLoader,
+			BrokerFixturesLoader: brokerFixturesLoader,
+			Mocks:                m,
+			MocksLoader:          mocksLoader,
+			Variables:            vars,
+			Parallel:             cfg.Parallel,
+			GrpcClient:           initGrpcClient(cfg),
+			GrpcHost:             cfg.GrpcHost,
+			Hooks:                hooks,
+			DB:                   storages.db,
 		},
-		yaml_file.NewLoader(cfg.TestsLocation),
+		initTestsLoader(cfg),
 	)
 }
 
+// initMocks starts the mocks of -mocks config and returns the variables with their addresses
+func initMocks(cfg config) (*mocks.Mocks, *variables.Variables) {
+	vars := variables.New()
+	if cfg.MocksConfig == "" {
+		return nil, vars
+	}
+
+	mocksCfg, err := loadMocksConfig(cfg.MocksConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m, err := startMocks(mocksCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	setMockAddrVariables(vars, m, mocksCfg)
+	return m, vars
+}
+
+func initTestsLoader(cfg config) *yaml_file.YamlFileLoader {
+	loader := yaml_file.NewLoader(cfg.TestsLocation)
+	loader.SetTagsFilter(splitList(cfg.Tags), splitList(cfg.ExcludeTags))
+	if cfg.Run != "" {
+		re, err := regexp.Compile(cfg.Run)
+		if err != nil {
+			log.Fatal("couldn't parse -run: ", err)
+		}
+		loader.SetNameFilter(re)
+	}
+	if cfg.Files != "" {
+		if err := loader.SetFileGlob(cfg.Files); err != nil {
+			log.Fatal("couldn't parse -files: ", err)
+		}
+	}
+	return loader
+}
+
+func initGrpcClient(cfg config) *grpc_client.Client {
+	if cfg.ProtoFiles == "" && cfg.Protosets == "" {
+		return nil
+	}
+
+	client, err := grpc_client.New(grpc_client.Config{
+		ProtoFiles:  splitList(cfg.ProtoFiles),
+		ImportPaths: splitList(cfg.ProtoImportPaths),
+		Protosets:   splitList(cfg.Protosets),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return client
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func initBroker(cfg config) broker.Broker {
+	if cfg.KafkaBrokers == "" {
+		return nil
+	}
+	return kafka.New(splitList(cfg.KafkaBrokers)...)
+}
+
 func initAerospike(cfg config) *aerospikeAdapter.Client {
 	if cfg.AerospikeHost != "" {
 		address, port, namespace := parseAerospikeHost(cfg.AerospikeHost)
@@ -171,17 +449,46 @@ func initAerospike(cfg config) *aerospikeAdapter.Client {
 	return nil
 }
 
+func initMongo(cfg config) *mongo.Database {
+	if cfg.DbType != fixtures.MongoParam || cfg.DbDsn == "" {
+		return nil
+	}
+
+	cs, err := connstring.ParseAndValidate(cfg.DbDsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cs.Database == "" {
+		log.Fatal(errors.New("database name must be given in db_dsn, e.g. mongodb://localhost:27017/test"))
+	}
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(cfg.DbDsn))
+	if err != nil {
+		log.Fatal("Couldn't connect to mongo: ", err)
+	}
+	return client.Database(cs.Database)
+}
+
+// sqlDrivers maps db-type to the name of database/sql driver
+var sqlDrivers = map[string]string{
+	fixtures.PostgresParam: "postgres",
+	fixtures.MysqlParam:    "mysql",
+	fixtures.SqliteParam:   "sqlite3",
+}
+
 func initDB(cfg config) *sql.DB {
-	if cfg.DbDsn != "" {
-		var err error
-		db, err := sql.Open("postgres", cfg.DbDsn)
-		if err != nil {
-			log.Fatal(err)
-		}
-		return db
+	if cfg.DbDsn == "" {
+		return nil
+	}
+	driver, ok := sqlDrivers[cfg.DbType]
+	if !ok {
+		return nil
 	}
 
-	return nil
+	db, err := sql.Open(driver, cfg.DbDsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return db
 }
 
 func getConfig() config {
@@ -195,13 +502,36 @@ func getConfig() config {
 	flag.StringVar(&cfg.FixturesLocation, "fixtures", "", "Path to fixtures directory")
 	flag.StringVar(&cfg.EnvFile, "env-file", "", "Path to env-file")
 	flag.BoolVar(&cfg.Allure, "allure", true, "Make Allure report")
+	flag.StringVar(&cfg.JUnitReport, "junit", "", "Path to JUnit XML report file")
+	flag.StringVar(&cfg.JSONReport, "json-report", "", "Path to machine-readable JSON report file")
+	flag.StringVar(&cfg.JSONReportFormat, "json-report-format", "ndjson", "Format of JSON report (options: ndjson, json)")
 	flag.BoolVar(&cfg.Verbose, "v", false, "Verbose output")
 	flag.BoolVar(&cfg.Debug, "debug", false, "Debug output")
+	flag.StringVar(&cfg.GrpcHost, "grpc-host", "", "gRPC server address in form of 'host:port' for tests with grpc section")
+	flag.StringVar(&cfg.ProtoFiles, "proto", "", "Comma-separated list of .proto files with gRPC services")
+	flag.StringVar(&cfg.ProtoImportPaths, "proto-import-path", "", "Comma-separated list of paths to resolve imports of .proto files")
+	flag.StringVar(&cfg.Protosets, "protoset", "", "Comma-separated list of protoset files with gRPC services")
+	flag.StringVar(&cfg.KafkaBrokers, "kafka-brokers", "", "Comma-separated list of Kafka brokers for broker fixtures and checks")
+	flag.DurationVar(&cfg.BrokerTimeout, "broker-timeout", response_broker.DefaultTimeout, "Time given to read messages of the topics checked by brokerChecks")
+	flag.StringVar(&cfg.Tags, "tags", "", "Comma-separated list of tags, only tests with any of them are run")
+	flag.StringVar(&cfg.ExcludeTags, "exclude-tags", "", "Comma-separated list of tags, tests with any of them are not run")
+	flag.StringVar(&cfg.Run, "run", "", "Regular expression, only tests with matching names are run")
+	flag.StringVar(&cfg.Files, "files", "", "Glob pattern of test files to run, matched against the path relative to -tests and against the file name")
+	flag.StringVar(&cfg.HooksFile, "hooks", "", "Path to file with beforeAll and afterAll hooks of the run")
+	flag.StringVar(&cfg.MocksConfig, "mocks", "", "Path to YAML file with the mocks started for the tests, their addresses are available as {{ $mock_addr_<service> }}")
+	flag.IntVar(&cfg.Parallel, "parallel", 1, "Number of tests executed concurrently")
+	flag.BoolVar(&cfg.Update, "update", false, "Rewrite expected response bodies and DB responses of failed tests with actual values")
+	flag.StringVar(&cfg.MocksDir, "mocks-dir", "", "Path to directory with the files replied by the mocks, tests using the changed files are re-run in -watch mode")
+	flag.BoolVar(&cfg.Watch, "watch", false, "Re-run tests affected by changes of the tests and fixtures files until interrupted")
+	flag.BoolVar(&cfg.Load, "load", false, "Run tests as a load test")
+	flag.DurationVar(&cfg.LoadDuration, "load-duration", 10*time.Second, "Duration of the load test")
+	flag.IntVar(&cfg.LoadRPS, "load-rps", 0, "Requests per second of the load test, requests are sent without pauses if not set")
+	flag.IntVar(&cfg.LoadConcurrency, "load-concurrency", 1, "Number of requests of the load test sent at the same time")
 	flag.StringVar(
 		&cfg.DbType,
 		"db-type",
 		fixtures.PostgresParam,
-		"Type of database (options: postgres, mysql, aerospike)",
+		"Type of database (options: postgres, mysql, sqlite, aerospike, redis, mongo)",
 	)
 
 	flag.Parse()
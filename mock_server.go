@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lamoda/gonkey/mocks"
+)
+
+// mockServer runs the mocks as a standalone process configured over the admin API:
+// gonkey mock-server [-config <...>] [-services <name[=addr],...>] [-admin <...>] [-definitions <...>] [-strict]
+func mockServer(args []string) {
+	flags := flag.NewFlagSet("mock-server", flag.ExitOnError)
+	configFile := flags.String("config", "", "Path to YAML file with the mocks, the same as -mocks file of the tests run")
+	services := flags.String("services", "", "Comma-separated names of the mocks, name=host:port sets the address, a random port is used otherwise")
+	admin := flags.String("admin", "", "Address of the admin API (default \":8090\")")
+	definitionsFile := flags.String("definitions", "", "Path to YAML file with the definitions of the mocks, in the format of `mocks` section of the test")
+	strict := flags.Bool("strict", false, "Report the requests which are not expected by the definitions")
+	_ = flags.Parse(args)
+
+	cfg := &mocksConfig{}
+	if *configFile != "" {
+		var err error
+		if cfg, err = loadMocksConfig(*configFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cfg.Services == nil {
+		cfg.Services = make(map[string]string)
+	}
+	for name, addr := range parseMockServices(*services) {
+		cfg.Services[name] = addr
+	}
+	cfg.Strict = cfg.Strict || *strict
+	if *admin != "" {
+		cfg.Admin = *admin
+	}
+	if cfg.Admin == "" {
+		cfg.Admin = ":8090"
+	}
+
+	m, err := startMocks(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *definitionsFile != "" {
+		data, err := ioutil.ReadFile(*definitionsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var definitions map[string]interface{}
+		if err := yaml.Unmarshal(data, &definitions); err != nil {
+			log.Fatalf("unable to parse %s: %s", *definitionsFile, err)
+		}
+		if err := mocks.NewLoader(m).Load(definitions); err != nil {
+			log.Fatal(err)
+		}
+	}
+	m.ResetRunningContext()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	m.Shutdown()
+}
+
+// parseMockServices parses the list of the mocks into their addresses, empty for random ports
+func parseMockServices(services string) map[string]string {
+	res := make(map[string]string)
+	for _, s := range strings.Split(services, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		name, addr := s, ""
+		if i := strings.Index(s, "="); i != -1 {
+			name, addr = s[:i], s[i+1:]
+		}
+		res[name] = addr
+	}
+	return res
+}
@@ -0,0 +1,161 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// adminCall is the request received by the mock in the replies of the admin API
+type adminCall struct {
+	Service      string      `json:"service"`
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	Query        string      `json:"query,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	RequestBody  string      `json:"requestBody,omitempty"`
+	StatusCode   int         `json:"statusCode"`
+	ResponseBody string      `json:"responseBody,omitempty"`
+	Time         time.Time   `json:"time"`
+}
+
+// AdminHandler returns HTTP API which configures the mocks at runtime:
+//
+//	GET  /mocks        addresses of the mocks
+//	POST /definitions  loads the definitions, the body is `mocks` section of the test in YAML or JSON
+//	POST /reset        resets the definitions, the received requests and the scenario states
+//	GET  /calls        requests received since the reset, `service` query parameter selects the mock
+//	POST /verify       ends the running context and returns the errors, e.g. unhandled requests or wrong number of calls
+func (m *Mocks) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mocks", m.adminMethod(http.MethodGet, m.handleAdminMocks))
+	mux.HandleFunc("/definitions", m.adminMethod(http.MethodPost, m.handleAdminDefinitions))
+	mux.HandleFunc("/reset", m.adminMethod(http.MethodPost, m.handleAdminReset))
+	mux.HandleFunc("/calls", m.adminMethod(http.MethodGet, m.handleAdminCalls))
+	mux.HandleFunc("/verify", m.adminMethod(http.MethodPost, m.handleAdminVerify))
+	return mux
+}
+
+// StartAdmin starts the server of the admin API on the address, see AdminHandler
+func (m *Mocks) StartAdmin(addr string) error {
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	m.adminListener = ln
+	m.adminServer = &http.Server{Addr: addr, Handler: m.AdminHandler()}
+	go m.adminServer.Serve(ln)
+	return nil
+}
+
+// AdminAddr returns the address of the admin API server
+func (m *Mocks) AdminAddr() string {
+	if m.adminListener == nil {
+		panic("admin server of the mocks is not started")
+	}
+	return m.adminListener.Addr().String()
+}
+
+func (m *Mocks) shutdownAdmin(ctx context.Context) error {
+	if m.adminServer == nil {
+		return nil
+	}
+	err := m.adminServer.Shutdown(ctx)
+	m.adminServer = nil
+	m.adminListener = nil
+	return err
+}
+
+func (m *Mocks) adminMethod(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed, use %s", r.Method, method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (m *Mocks) handleAdminMocks(w http.ResponseWriter, r *http.Request) {
+	addrs := make(map[string]string, len(m.mocks))
+	for name, v := range m.mocks {
+		if v.listener != nil {
+			addrs[name] = v.ServerAddr()
+		} else {
+			addrs[name] = ""
+		}
+	}
+	writeAdminReply(w, http.StatusOK, addrs)
+}
+
+func (m *Mocks) handleAdminDefinitions(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	var definitions map[string]interface{}
+	if err := yaml.Unmarshal(body, &definitions); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("unable to parse definitions: %w", err))
+		return
+	}
+	if err := NewLoader(m).Load(definitions); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	services := make([]string, 0, len(definitions))
+	for name := range definitions {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	writeAdminReply(w, http.StatusOK, map[string][]string{"loaded": services})
+}
+
+func (m *Mocks) handleAdminReset(w http.ResponseWriter, r *http.Request) {
+	m.ResetDefinitions()
+	m.ResetRunningContext()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Mocks) handleAdminCalls(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	if service != "" && m.Service(service) == nil {
+		writeAdminError(w, http.StatusNotFound, fmt.Errorf("service mock not defined: %s", service))
+		return
+	}
+
+	calls := []adminCall{}
+	for _, c := range m.Calls() {
+		if service == "" || c.Service == service {
+			calls = append(calls, adminCall(c))
+		}
+	}
+	writeAdminReply(w, http.StatusOK, calls)
+}
+
+func (m *Mocks) handleAdminVerify(w http.ResponseWriter, r *http.Request) {
+	errs := []string{}
+	for _, err := range m.EndRunningContext() {
+		errs = append(errs, err.Error())
+	}
+	writeAdminReply(w, http.StatusOK, map[string][]string{"errors": errs})
+}
+
+func writeAdminReply(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, statusCode int, err error) {
+	writeAdminReply(w, statusCode, map[string]string{"error": err.Error()})
+}
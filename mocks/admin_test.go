@@ -0,0 +1,98 @@
+package mocks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func adminRequest(t *testing.T, method, url, body string, reply interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	if reply != nil {
+		require.NoError(t, json.Unmarshal(data, reply), string(data))
+	}
+	return resp.StatusCode
+}
+
+func TestAdminAPI(t *testing.T) {
+	m := NewNop("orders", "stock")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+	admin := httptest.NewServer(m.AdminHandler())
+	defer admin.Close()
+
+	var addrs map[string]string
+	require.Equal(t, http.StatusOK, adminRequest(t, http.MethodGet, admin.URL+"/mocks", "", &addrs))
+	require.Equal(t, m.Service("orders").ServerAddr(), addrs["orders"])
+
+	var loaded map[string][]string
+	code := adminRequest(t, http.MethodPost, admin.URL+"/definitions", `
+orders:
+  strategy: constant
+  body: '{"id": 1}'
+  calls: 2
+`, &loaded)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []string{"orders"}, loaded["loaded"])
+
+	_, _, body := doMockRequest(t, http.MethodPost, "http://"+addrs["orders"]+"/orders", `{"sku": "a1"}`)
+	require.Equal(t, `{"id": 1}`, body)
+
+	var calls []adminCall
+	require.Equal(t, http.StatusOK, adminRequest(t, http.MethodGet, admin.URL+"/calls?service=orders", "", &calls))
+	require.Len(t, calls, 1)
+	require.Equal(t, "/orders", calls[0].Path)
+	require.Equal(t, `{"sku": "a1"}`, calls[0].RequestBody)
+	require.Equal(t, `{"id": 1}`, calls[0].ResponseBody)
+
+	var verified map[string][]string
+	require.Equal(t, http.StatusOK, adminRequest(t, http.MethodPost, admin.URL+"/verify", "", &verified))
+	require.Len(t, verified["errors"], 1)
+	require.Contains(t, verified["errors"][0], "number of calls does not match: expected 2, actual 1")
+
+	require.Equal(t, http.StatusNoContent, adminRequest(t, http.MethodPost, admin.URL+"/reset", "", nil))
+	require.Equal(t, http.StatusOK, adminRequest(t, http.MethodGet, admin.URL+"/calls", "", &calls))
+	require.Empty(t, calls)
+
+	// JSON is accepted too
+	code = adminRequest(t, http.MethodPost, admin.URL+"/definitions", `{"stock": {"strategy": "nop"}}`, &loaded)
+	require.Equal(t, http.StatusOK, code)
+	code, _, _ = doMockRequest(t, http.MethodGet, "http://"+addrs["stock"]+"/reserve", "")
+	require.Equal(t, http.StatusNoContent, code)
+	require.Equal(t, http.StatusOK, adminRequest(t, http.MethodPost, admin.URL+"/verify", "", &verified))
+	require.Empty(t, verified["errors"])
+
+	var failed map[string]string
+	code = adminRequest(t, http.MethodPost, admin.URL+"/definitions", `{"cart": {"strategy": "nop"}}`, &failed)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "service mock not defined: cart", failed["error"])
+	require.Equal(t, http.StatusNotFound, adminRequest(t, http.MethodGet, admin.URL+"/calls?service=cart", "", &failed))
+	require.Equal(t, http.StatusMethodNotAllowed, adminRequest(t, http.MethodGet, admin.URL+"/definitions", "", &failed))
+}
+
+func TestStartAdmin(t *testing.T) {
+	m := NewNop("orders")
+	require.NoError(t, m.Start())
+	require.NoError(t, m.StartAdmin("localhost:0"))
+
+	adminAddr := m.AdminAddr()
+
+	var addrs map[string]string
+	require.Equal(t, http.StatusOK, adminRequest(t, http.MethodGet, "http://"+adminAddr+"/mocks", "", &addrs))
+	require.Contains(t, addrs, "orders")
+
+	m.Shutdown()
+	_, err := http.Get("http://" + adminAddr + "/mocks")
+	require.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+// callRecorder passes the reply of the mock to the client and remembers its status and body
+type callRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newCallRecorder(w http.ResponseWriter) *callRecorder {
+	return &callRecorder{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+}
+
+func (w *callRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *callRecorder) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *callRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *callRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer doesn't support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// readRequestBody reads the body of the request leaving it available for the mock definition
+func readRequestBody(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return ""
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return string(body)
+}
+
+func newMockCall(serviceName string, r *http.Request, requestBody string, w *callRecorder, startedAt time.Time) models.MockCall {
+	return models.MockCall{
+		Service:      serviceName,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Query:        r.URL.RawQuery,
+		Headers:      r.Header,
+		RequestBody:  requestBody,
+		StatusCode:   w.statusCode,
+		ResponseBody: w.body.String(),
+		Time:         startedAt,
+	}
+}
+
+// sortCalls orders calls of several services by the time they were made
+func sortCalls(calls []models.MockCall) {
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].Time.Before(calls[j].Time)
+	})
+}
@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const callsMockDefinition = `
+orders:
+  strategy: constant
+  body: '{"id": 1}'
+  statusCode: 201
+stock:
+  strategy: constant
+  body: ok
+`
+
+func TestMocksCalls(t *testing.T) {
+	m := NewNop("orders", "stock")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	var def map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(callsMockDefinition), &def))
+	require.NoError(t, NewLoader(m).Load(def))
+
+	resp, err := http.Post("http://"+m.Service("orders").ServerAddr()+"/orders?user=42", "application/json", strings.NewReader(`{"sku": "a1"}`))
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, `{"id": 1}`, string(body))
+
+	resp, err = http.Get("http://" + m.Service("stock").ServerAddr() + "/reserve")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	calls := m.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, "orders", calls[0].Service)
+	require.Equal(t, "POST", calls[0].Method)
+	require.Equal(t, "/orders", calls[0].Path)
+	require.Equal(t, "user=42", calls[0].Query)
+	require.Equal(t, `{"sku": "a1"}`, calls[0].RequestBody)
+	require.Equal(t, 201, calls[0].StatusCode)
+	require.Equal(t, `{"id": 1}`, calls[0].ResponseBody)
+	require.Equal(t, "stock", calls[1].Service)
+	require.Equal(t, "/reserve", calls[1].Path)
+
+	m.ResetRunningContext()
+	require.Empty(t, m.Calls())
+}
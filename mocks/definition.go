@@ -16,6 +16,8 @@ type definition struct {
 	sync.Mutex
 	calls           int
 	callsConstraint int
+	// transition of the scenario state made when the request matches the constraints, nil if the state is not changed
+	transition *stateTransition
 }
 
 func newDefinition(path string, constraints []verifier, strategy replyStrategy, callsConstraint int) *definition {
@@ -31,6 +33,9 @@ func (d *definition) Execute(w http.ResponseWriter, r *http.Request) []error {
 	d.Lock()
 	d.calls++
 	d.Unlock()
+
+	// the state of the scenario is checked and changed at once, before the reply which may take time
+	unlock := d.lockScenarios()
 	var errors []error
 	if len(d.requestConstraints) > 0 {
 		requestDump, err := httputil.DumpRequest(r, true)
@@ -49,6 +54,11 @@ func (d *definition) Execute(w http.ResponseWriter, r *http.Request) []error {
 			}
 		}
 	}
+	if len(errors) == 0 && d.transition != nil {
+		d.transition.apply()
+	}
+	unlock()
+
 	if d.replyStrategy != nil {
 		errors = append(errors, d.replyStrategy.HandleRequest(w, r)...)
 	}
@@ -99,6 +109,8 @@ func verifyRequestConstraints(requestConstraints []verifier, r *http.Request) []
 	}
 	return errors
 }
+// ExecuteWithoutVerifying replies to the request which is verified by the caller,
+// the caller makes the transition of the scenario state as well
 func (d *definition) ExecuteWithoutVerifying(w http.ResponseWriter, r *http.Request) []error {
 	d.Lock()
 	d.calls++
@@ -0,0 +1,214 @@
+package mocks
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// wrappedReply is a reply strategy which changes the way the reply of the nested definition is sent
+type wrappedReply struct {
+	reply *definition
+}
+
+func (s *wrappedReply) ResetRunningContext() {
+	s.reply.ResetRunningContext()
+}
+
+func (s *wrappedReply) EndRunningContext() []error {
+	return s.reply.EndRunningContext()
+}
+
+// bufferReply executes the nested definition and returns its reply without sending it
+func (s *wrappedReply) bufferReply(r *http.Request) (*httptest.ResponseRecorder, []error) {
+	rec := httptest.NewRecorder()
+	errs := s.reply.Execute(rec, r)
+	return rec, errs
+}
+
+// delayReply sends the reply after the delay, or gives up if the client has gone
+type delayReply struct {
+	wrappedReply
+	delay time.Duration
+}
+
+func newDelayReply(delay time.Duration, reply *definition) replyStrategy {
+	return &delayReply{
+		wrappedReply: wrappedReply{reply: reply},
+		delay:        delay,
+	}
+}
+
+func (s *delayReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	select {
+	case <-time.After(s.delay):
+	case <-r.Context().Done():
+		return nil
+	}
+	return s.reply.Execute(w, r)
+}
+
+// dropConnectionReply closes the connection without replying
+type dropConnectionReply struct{}
+
+func (s *dropConnectionReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	return dropConnection(w)
+}
+
+func dropConnection(w http.ResponseWriter) []error {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return []error{errors.New("the connection of the mock can't be dropped")}
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return []error{err}
+	}
+	_ = conn.Close()
+	return nil
+}
+
+// partialBodyReply sends the headers of the reply with the full Content-Length
+// and only the first bytes of the body, then closes the connection
+type partialBodyReply struct {
+	wrappedReply
+	// bytes is the number of bytes sent, negative for the half of the body
+	bytes int
+}
+
+func newPartialBodyReply(bytes int, reply *definition) replyStrategy {
+	return &partialBodyReply{
+		wrappedReply: wrappedReply{reply: reply},
+		bytes:        bytes,
+	}
+}
+
+func (s *partialBodyReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	rec, errs := s.bufferReply(r)
+	body := rec.Body.Bytes()
+	n := s.bytes
+	if n < 0 || n > len(body) {
+		n = len(body) / 2
+	}
+
+	copyHeaders(w.Header(), rec.Header())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(rec.Code)
+	w.Write(body[:n])
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return append(errs, dropConnection(w)...)
+}
+
+// slowBodyReply sends the body of the reply with the limited speed
+type slowBodyReply struct {
+	wrappedReply
+	bytesPerSecond int
+}
+
+// slowBodyInterval is the interval between the chunks of the body
+const slowBodyInterval = 100 * time.Millisecond
+
+func newSlowBodyReply(bytesPerSecond int, reply *definition) replyStrategy {
+	return &slowBodyReply{
+		wrappedReply:   wrappedReply{reply: reply},
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+func (s *slowBodyReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	rec, errs := s.bufferReply(r)
+	body := rec.Body.Bytes()
+
+	copyHeaders(w.Header(), rec.Header())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(rec.Code)
+
+	chunk := s.bytesPerSecond * int(slowBodyInterval) / int(time.Second)
+	if chunk < 1 {
+		chunk = 1
+	}
+	flusher, _ := w.(http.Flusher)
+	for len(body) != 0 {
+		n := chunk
+		if n > len(body) {
+			n = len(body)
+		}
+		if _, err := w.Write(body[:n]); err != nil {
+			return errs
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		body = body[n:]
+		if len(body) == 0 {
+			break
+		}
+		select {
+		case <-time.After(slowBodyInterval):
+		case <-r.Context().Done():
+			return errs
+		}
+	}
+	return errs
+}
+
+// randomFailureReply replies with the failure with the given probability and with the nested reply otherwise
+type randomFailureReply struct {
+	wrappedReply
+	probability float64
+	failure     *definition
+
+	sync.Mutex
+	rnd *rand.Rand
+}
+
+func newRandomFailureReply(probability float64, seed int64, reply, failure *definition) replyStrategy {
+	return &randomFailureReply{
+		wrappedReply: wrappedReply{reply: reply},
+		probability:  probability,
+		failure:      failure,
+		rnd:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *randomFailureReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	s.Lock()
+	failed := s.rnd.Float64() < s.probability
+	s.Unlock()
+
+	if !failed {
+		return s.reply.Execute(w, r)
+	}
+	if s.failure == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+	return s.failure.Execute(w, r)
+}
+
+func (s *randomFailureReply) ResetRunningContext() {
+	s.wrappedReply.ResetRunningContext()
+	if s.failure != nil {
+		s.failure.ResetRunningContext()
+	}
+}
+
+func (s *randomFailureReply) EndRunningContext() []error {
+	errs := s.wrappedReply.EndRunningContext()
+	if s.failure != nil {
+		errs = append(errs, s.failure.EndRunningContext()...)
+	}
+	return errs
+}
+
+func copyHeaders(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
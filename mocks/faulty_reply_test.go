@@ -0,0 +1,258 @@
+package mocks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startFaultyMock(t *testing.T, definition string) (*Mocks, string) {
+	m := NewNop("stock")
+	require.NoError(t, m.Start())
+	loadMocksDefinition(t, m, definition)
+	m.ResetRunningContext()
+	return m, "http://" + m.Service("stock").ServerAddr()
+}
+
+func TestDelayReply(t *testing.T) {
+	m, addr := startFaultyMock(t, `
+stock:
+  strategy: delay
+  duration: 200
+  reply:
+    strategy: constant
+    body: ok
+`)
+	defer m.Shutdown()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	_, err := client.Get(addr + "/items")
+	require.Error(t, err)
+
+	startedAt := time.Now()
+	_, _, body := doMockRequest(t, http.MethodGet, addr+"/items", "")
+	require.Equal(t, "ok", body)
+	require.True(t, time.Since(startedAt) >= 200*time.Millisecond)
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestDropConnectionReply(t *testing.T) {
+	m, addr := startFaultyMock(t, `
+stock:
+  strategy: dropConnection
+`)
+	defer m.Shutdown()
+
+	_, err := http.Get(addr + "/items")
+	require.Error(t, err)
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestPartialBodyReply(t *testing.T) {
+	m, addr := startFaultyMock(t, `
+stock:
+  strategy: partialBody
+  bytes: 4
+  reply:
+    strategy: constant
+    body: '{"items": []}'
+    headers:
+      Content-Type: application/json
+`)
+	defer m.Shutdown()
+
+	resp, err := http.Get(addr + "/items")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, int64(13), resp.ContentLength)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Error(t, err)
+	require.Equal(t, `{"it`, string(body))
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestSlowBodyReply(t *testing.T) {
+	m, addr := startFaultyMock(t, `
+stock:
+  strategy: slowBody
+  bytesPerSecond: 100
+  reply:
+    strategy: constant
+    body: '{"items": ["a1", "b2", "c3"]}'
+`)
+	defer m.Shutdown()
+
+	startedAt := time.Now()
+	code, _, body := doMockRequest(t, http.MethodGet, addr+"/items", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `{"items": ["a1", "b2", "c3"]}`, body)
+	// 29 bytes are sent by 10 bytes every 100ms
+	require.True(t, time.Since(startedAt) >= 200*time.Millisecond)
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestRandomFailureReply(t *testing.T) {
+	const definition = `
+stock:
+  strategy: randomFailure
+  probability: 0.5
+  seed: 42
+  reply:
+    strategy: constant
+    body: ok
+`
+	codes := func() []int {
+		m, addr := startFaultyMock(t, definition)
+		defer m.Shutdown()
+
+		var res []int
+		for i := 0; i < 20; i++ {
+			code, _, _ := doMockRequest(t, http.MethodGet, addr+"/items", "")
+			res = append(res, code)
+		}
+		require.Empty(t, m.EndRunningContext())
+		return res
+	}
+
+	first := codes()
+	require.Contains(t, first, http.StatusOK)
+	require.Contains(t, first, http.StatusInternalServerError)
+	// the same seed gives the same failures
+	require.Equal(t, first, codes())
+}
+
+func TestRandomFailureReplyWithFailure(t *testing.T) {
+	m, addr := startFaultyMock(t, `
+stock:
+  strategy: randomFailure
+  probability: 1
+  reply:
+    strategy: constant
+    body: ok
+  failure:
+    strategy: dropConnection
+`)
+	defer m.Shutdown()
+
+	_, err := http.Get(addr + "/items")
+	require.Error(t, err)
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestDelayReplyDoesNotBlockMock(t *testing.T) {
+	m, addr := startFaultyMock(t, `
+stock:
+  strategy: uriVary
+  uris:
+    /slow:
+      strategy: delay
+      duration: 1000
+      reply:
+        strategy: constant
+        body: slow
+    /fast:
+      strategy: constant
+      body: fast
+`)
+	defer m.Shutdown()
+
+	slowDone := make(chan string)
+	go func() {
+		_, _, body := doMockRequest(t, http.MethodGet, addr+"/slow", "")
+		slowDone <- body
+	}()
+	// let the slow request reach the mock
+	time.Sleep(100 * time.Millisecond)
+
+	startedAt := time.Now()
+	_, _, body := doMockRequest(t, http.MethodGet, addr+"/fast", "")
+	require.Equal(t, "fast", body)
+	require.Len(t, m.Calls(), 1)
+	require.True(t, time.Since(startedAt) < 500*time.Millisecond)
+
+	require.Equal(t, "slow", <-slowDone)
+	require.Len(t, m.Calls(), 2)
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestConcurrentRequestsToStatefulStrategies(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+	filename := filepath.Join(t.TempDir(), "replay.yaml")
+
+	const requests = 10
+	var sequence strings.Builder
+	for i := 0; i < requests; i++ {
+		fmt.Fprintf(&sequence, `
+        - strategy: delay
+          duration: 50
+          reply:
+            strategy: constant
+            body: "%d"`, i)
+	}
+	m, addr := startFaultyMock(t, fmt.Sprintf(`
+stock:
+  strategy: uriVary
+  uris:
+    /sequence:
+      strategy: sequence
+      sequence:%s
+    /record:
+      strategy: record
+      url: %s
+      filename: %s
+    /random:
+      strategy: randomFailure
+      probability: 0.5
+      reply:
+        strategy: constant
+        body: ok
+    /scenario:
+      strategy: constant
+      body: ok
+      scenario: orders
+      newState: created
+`, sequence.String(), upstream.URL, filename))
+	defer m.Shutdown()
+
+	var wg sync.WaitGroup
+	bodies := make(chan string, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, body := doMockRequest(t, http.MethodGet, addr+"/sequence", "")
+			bodies <- body
+			doMockRequest(t, http.MethodGet, addr+"/record", "")
+			doMockRequest(t, http.MethodGet, addr+"/random", "")
+			doMockRequest(t, http.MethodGet, addr+"/scenario", "")
+		}()
+	}
+	wg.Wait()
+	close(bodies)
+
+	// every reply of the sequence is made once
+	seen := make(map[string]bool)
+	for body := range bodies {
+		require.False(t, seen[body], "reply %s is made twice", body)
+		seen[body] = true
+	}
+	require.Len(t, seen, requests)
+	require.Len(t, m.Calls(), 4*requests)
+	require.Empty(t, m.EndRunningContext())
+
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, requests, strings.Count(string(data), "upstream"))
+}
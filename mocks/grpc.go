@@ -0,0 +1,207 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	grpcStatusHeader  = "Grpc-Status"
+	grpcMessageHeader = "Grpc-Message"
+)
+
+// GrpcMethodResolver finds descriptors of the methods served by gRPC mocks,
+// it is implemented by *grpc_client.Client
+type GrpcMethodResolver interface {
+	FindMethod(service, method string) (protoreflect.MethodDescriptor, error)
+}
+
+// NewGrpcServiceMock creates the mock of gRPC service. Unary calls are converted to HTTP requests
+// to reuse definitions of HTTP mocks: the path is /<service>/<method>, the body is the request message
+// in JSON and the headers are the request metadata. The body of the reply is converted to the reply message.
+func NewGrpcServiceMock(serviceName string, resolver GrpcMethodResolver) *ServiceMock {
+	m := NewServiceMock(serviceName, newDefinition("$", nil, &failReply{}, callsNoConstraint))
+	m.grpcResolver = resolver
+	return m
+}
+
+func (m *ServiceMock) startGrpcServer(addr string) error {
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	m.listener = ln
+	m.grpcServer = grpc.NewServer(grpc.UnknownServiceHandler(m.handleGrpcStream))
+	go m.grpcServer.Serve(ln)
+	return nil
+}
+
+func (m *ServiceMock) handleGrpcStream(_ interface{}, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) != 2 {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+
+	methodDesc, err := m.grpcResolver.FindMethod(parts[0], parts[1])
+	if err != nil {
+		m.addError(fmt.Errorf("unhandled gRPC call %s: %w", fullMethod, err))
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+
+	req := dynamicpb.NewMessage(methodDesc.Input())
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	body, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(req)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	r, err := http.NewRequestWithContext(stream.Context(), http.MethodPost, fullMethod, bytes.NewReader(body))
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		for k, values := range md {
+			if k == "content-type" || strings.HasPrefix(k, ":") {
+				continue
+			}
+			for _, v := range values {
+				r.Header.Add(textproto.CanonicalMIMEHeaderKey(k), v)
+			}
+		}
+	}
+
+	w := newGrpcResponseWriter()
+	m.ServeHTTP(w, r)
+
+	return m.sendGrpcReply(stream, methodDesc, w)
+}
+
+func (m *ServiceMock) sendGrpcReply(stream grpc.ServerStream, methodDesc protoreflect.MethodDescriptor, w *grpcResponseWriter) error {
+	header := metadata.MD{}
+	for k, v := range w.header {
+		if k != grpcStatusHeader && k != grpcMessageHeader {
+			header[strings.ToLower(k)] = v
+		}
+	}
+	if len(header) != 0 {
+		_ = stream.SetHeader(header)
+	}
+
+	if code := w.header.Get(grpcStatusHeader); code != "" {
+		c, err := strconv.Atoi(code)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		return status.Error(codes.Code(c), w.header.Get(grpcMessageHeader))
+	}
+	if !w.wroteHeader {
+		return status.Error(codes.Unimplemented, "unhandled request to mock")
+	}
+	if w.statusCode >= http.StatusBadRequest {
+		return status.Errorf(codes.Unknown, "mock replied with status %d: %s", w.statusCode, w.body.String())
+	}
+
+	reply := dynamicpb.NewMessage(methodDesc.Output())
+	if w.body.Len() != 0 {
+		if err := protojson.Unmarshal(w.body.Bytes(), reply); err != nil {
+			err = fmt.Errorf("unable to convert reply to %s: %w", methodDesc.Output().FullName(), err)
+			m.addError(err)
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	return stream.SendMsg(reply)
+}
+
+func (m *ServiceMock) addError(err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.errors = append(m.errors, err)
+}
+
+func (m *ServiceMock) shutdownGrpcServer(ctx context.Context) error {
+	srv := m.grpcServer
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+	m.listener = nil
+	m.grpcServer = nil
+	return nil
+}
+
+// grpcResponseWriter collects the reply of the mock definition
+type grpcResponseWriter struct {
+	header      http.Header
+	body        bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+}
+
+func newGrpcResponseWriter() *grpcResponseWriter {
+	return &grpcResponseWriter{
+		header:     make(http.Header),
+		statusCode: http.StatusOK,
+	}
+}
+
+func (w *grpcResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(b)
+}
+
+func (w *grpcResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.statusCode = statusCode
+	w.wroteHeader = true
+}
+
+// grpcStatusReply replies to gRPC call with the error status
+type grpcStatusReply struct {
+	code    codes.Code
+	message string
+}
+
+func newGrpcStatusReply(code codes.Code, message string) replyStrategy {
+	return &grpcStatusReply{
+		code:    code,
+		message: message,
+	}
+}
+
+func (s *grpcStatusReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	w.Header().Set(grpcStatusHeader, strconv.Itoa(int(s.code)))
+	w.Header().Set(grpcMessageHeader, s.message)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
@@ -0,0 +1,98 @@
+package mocks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lamoda/gonkey/grpc_client"
+)
+
+const grpcMockDefinition = `
+greeter:
+  strategy: uriVary
+  uris:
+    /helloworld.Greeter/SayHello:
+      requestConstraints:
+        - kind: bodyMatchesJSON
+          body: '{"name": "John"}'
+        - kind: headerIs
+          header: x-request-id
+          value: "42"
+      strategy: constant
+      body: '{"message": "Hello John", "length": 10}'
+      headers:
+        x-mock: greeter
+      calls: 1
+`
+
+const grpcStatusMockDefinition = `
+greeter:
+  strategy: grpcStatus
+  code: NOT_FOUND
+  message: no greeting
+`
+
+func TestGrpcServiceMock(t *testing.T) {
+	client, m := startGrpcMocks(t, grpcMockDefinition)
+	addr := m.Service("greeter").ServerAddr()
+
+	resp, err := client.Invoke(context.Background(), addr, "helloworld.Greeter", "SayHello",
+		`{"name": "John"}`, map[string]string{"x-request-id": "42"})
+	require.NoError(t, err)
+	require.Equal(t, codes.OK, resp.Status.Code())
+	require.JSONEq(t, `{"message": "Hello John", "length": 10}`, resp.Message)
+	require.Equal(t, []string{"greeter"}, resp.Headers["X-Mock"])
+	require.Empty(t, m.EndRunningContext())
+
+	// the request doesn't match the constraints and the call count is exceeded
+	_, err = client.Invoke(context.Background(), addr, "helloworld.Greeter", "SayHello", `{"name": "Jane"}`, nil)
+	require.NoError(t, err)
+	require.Len(t, m.EndRunningContext(), 3)
+}
+
+func TestGrpcServiceMock_Status(t *testing.T) {
+	client, m := startGrpcMocks(t, grpcStatusMockDefinition)
+
+	resp, err := client.Invoke(context.Background(), m.Service("greeter").ServerAddr(),
+		"helloworld.Greeter", "SayHello", `{"name": "John"}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, codes.NotFound, resp.Status.Code())
+	require.Equal(t, "no greeting", resp.Status.Message())
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestGrpcServiceMock_Unhandled(t *testing.T) {
+	client, m := startGrpcMocks(t, "")
+
+	resp, err := client.Invoke(context.Background(), m.Service("greeter").ServerAddr(),
+		"helloworld.Greeter", "SayHello", `{"name": "John"}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, codes.Unimplemented, resp.Status.Code())
+	require.Len(t, m.EndRunningContext(), 1)
+}
+
+func startGrpcMocks(t *testing.T, definition string) (*grpc_client.Client, *Mocks) {
+	client, err := grpc_client.New(grpc_client.Config{
+		ProtoFiles:  []string{"greeter.proto"},
+		ImportPaths: []string{"testdata"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	m := New(NewGrpcServiceMock("greeter", client))
+	require.NoError(t, m.Start())
+	t.Cleanup(m.Shutdown)
+
+	if definition != "" {
+		var def map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(definition), &def))
+		require.NoError(t, NewLoader(m).Load(def))
+	}
+	m.ResetRunningContext()
+
+	return client, m
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/lamoda/gonkey/compare"
+	"google.golang.org/grpc/codes"
 )
 
 type Loader struct {
@@ -62,6 +64,8 @@ func (l *Loader) loadDefinition(path string, rawDef interface{}) (*definition, e
 		"requestConstraints",
 		"strategy",
 		"calls",
+		"scenario",
+		"newState",
 	}
 
 	// load reply strategy
@@ -85,11 +89,50 @@ func (l *Loader) loadDefinition(path string, rawDef interface{}) (*definition, e
 		}
 	}
 
+	transition, err := l.loadStateTransition(def)
+	if err != nil {
+		return nil, fmt.Errorf("at path %s: %v", path, err)
+	}
+
 	if err := validateMapKeys(def, ak...); err != nil {
 		return nil, err
 	}
 
-	return newDefinition(path, requestConstraints, replyStrategy, callsConstraint), nil
+	d := newDefinition(path, requestConstraints, replyStrategy, callsConstraint)
+	d.transition = transition
+	return d, nil
+}
+
+func (l *Loader) loadStateTransition(def map[interface{}]interface{}) (*stateTransition, error) {
+	scenario, err := loadScenarioName(def)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := def["newState"]
+	if !ok {
+		if _, ok := def["scenario"]; ok {
+			return nil, errors.New("`scenario` requires `newState` key")
+		}
+		return nil, nil
+	}
+	state, ok := s.(string)
+	if !ok || state == "" {
+		return nil, errors.New("`newState` must be string")
+	}
+	return &stateTransition{states: l.mocks.states, scenario: scenario, state: state}, nil
+}
+
+// loadScenarioName returns the name of the scenario given in `scenario` key or the default one
+func loadScenarioName(def map[interface{}]interface{}) (string, error) {
+	s, ok := def["scenario"]
+	if !ok {
+		return defaultScenario, nil
+	}
+	scenario, ok := s.(string)
+	if !ok || scenario == "" {
+		return "", errors.New("`scenario` must be string")
+	}
+	return scenario, nil
 }
 
 func (l *Loader) loadStrategy(path, strategyName string, definition map[interface{}]interface{}, ak *[]string) (replyStrategy, error) {
@@ -117,6 +160,32 @@ func (l *Loader) loadStrategy(path, strategyName string, definition map[interfac
 	case "basedOnRequest":
 		*ak = append(*ak, "basePath", "uris")
 		return l.loadBasedOnRequestStrategy(path, definition)
+	case "grpcStatus":
+		*ak = append(*ak, "code", "message")
+		return l.loadGrpcStatusStrategy(path, definition)
+	case "proxy":
+		*ak = append(*ak, "url")
+		return l.loadProxyStrategy(path, definition)
+	case "record":
+		*ak = append(*ak, "url", "filename", "replayAs")
+		return l.loadRecordStrategy(path, definition)
+	case "replay":
+		*ak = append(*ak, "filename")
+		return l.loadReplayStrategy(path, definition)
+	case "delay":
+		*ak = append(*ak, "duration", "reply")
+		return l.loadDelayStrategy(path, definition)
+	case "dropConnection":
+		return &dropConnectionReply{}, nil
+	case "partialBody":
+		*ak = append(*ak, "bytes", "reply")
+		return l.loadPartialBodyStrategy(path, definition)
+	case "slowBody":
+		*ak = append(*ak, "bytesPerSecond", "reply")
+		return l.loadSlowBodyStrategy(path, definition)
+	case "randomFailure":
+		*ak = append(*ak, "probability", "seed", "reply", "failure")
+		return l.loadRandomFailureStrategy(path, definition)
 	default:
 		return nil, fmt.Errorf("unknown strategy: %s", strategyName)
 	}
@@ -204,6 +273,32 @@ func (l *Loader) loadConstantStrategy(path string, def map[interface{}]interface
 	return newConstantReplyWithCode([]byte(body), statusCode, headers), nil
 }
 
+func (l *Loader) loadGrpcStatusStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	c, ok := def["code"]
+	if !ok {
+		return nil, errors.New("`grpcStatus` requires `code` key")
+	}
+	var code codes.Code
+	switch v := c.(type) {
+	case int:
+		code = codes.Code(v)
+	case string:
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(v))); err != nil {
+			return nil, fmt.Errorf("`code` is not a valid gRPC status code: %s", v)
+		}
+	default:
+		return nil, errors.New("`code` must be int or string")
+	}
+	var message string
+	if m, ok := def["message"]; ok {
+		message, ok = m.(string)
+		if !ok {
+			return nil, errors.New("`message` must be string")
+		}
+	}
+	return newGrpcStatusReply(code, message), nil
+}
+
 func (l *Loader) loadTemplateStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
 	c, ok := def["body"]
 	if !ok {
@@ -266,6 +361,159 @@ func (l *Loader) loadBasedOnRequestStrategy(path string, def map[interface{}]int
 	return newBasedOnRequestReply(uris), nil
 }
 
+func (l *Loader) loadProxyStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	u, ok := def["url"]
+	if !ok {
+		return nil, errors.New("`proxy` requires `url` key")
+	}
+	upstream, ok := u.(string)
+	if !ok {
+		return nil, errors.New("`url` must be string")
+	}
+	return newProxyReply(upstream, "", "")
+}
+
+func (l *Loader) loadRecordStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	u, ok := def["url"]
+	if !ok {
+		return nil, errors.New("`record` requires `url` key")
+	}
+	upstream, ok := u.(string)
+	if !ok {
+		return nil, errors.New("`url` must be string")
+	}
+	f, ok := def["filename"]
+	if !ok {
+		return nil, errors.New("`record` requires `filename` key")
+	}
+	filename, ok := f.(string)
+	if !ok {
+		return nil, errors.New("`filename` must be string")
+	}
+	replayAs := "sequence"
+	if r, ok := def["replayAs"]; ok {
+		replayAs, ok = r.(string)
+		if !ok || (replayAs != "sequence" && replayAs != "basedOnRequest") {
+			return nil, errors.New("`replayAs` must be `sequence` or `basedOnRequest`")
+		}
+	}
+	return newProxyReply(upstream, filename, replayAs)
+}
+
+func (l *Loader) loadReplayStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	f, ok := def["filename"]
+	if !ok {
+		return nil, errors.New("`replay` requires `filename` key")
+	}
+	filename, ok := f.(string)
+	if !ok {
+		return nil, errors.New("`filename` must be string")
+	}
+	raw, err := loadReplayDefinition(filename)
+	if err != nil {
+		return nil, err
+	}
+	replayed, err := l.loadDefinition(path, raw)
+	if err != nil {
+		return nil, err
+	}
+	return replayed.replyStrategy, nil
+}
+
+// loadNestedDefinition loads the definition of the reply modified by the strategy
+func (l *Loader) loadNestedDefinition(path, strategyName, key string, def map[interface{}]interface{}) (*definition, error) {
+	v, ok := def[key]
+	if !ok {
+		return nil, fmt.Errorf("`%s` requires `%s` key", strategyName, key)
+	}
+	return l.loadDefinition(path+"."+key, v)
+}
+
+func (l *Loader) loadDelayStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	d, ok := def["duration"]
+	if !ok {
+		return nil, errors.New("`delay` requires `duration` key")
+	}
+	duration, ok := d.(int)
+	if !ok || duration < 0 {
+		return nil, errors.New("`duration` must be a non-negative number of milliseconds")
+	}
+	reply, err := l.loadNestedDefinition(path, "delay", "reply", def)
+	if err != nil {
+		return nil, err
+	}
+	return newDelayReply(time.Duration(duration)*time.Millisecond, reply), nil
+}
+
+func (l *Loader) loadPartialBodyStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	bytes := -1
+	if b, ok := def["bytes"]; ok {
+		bytes, ok = b.(int)
+		if !ok || bytes < 0 {
+			return nil, errors.New("`bytes` must be a non-negative number")
+		}
+	}
+	reply, err := l.loadNestedDefinition(path, "partialBody", "reply", def)
+	if err != nil {
+		return nil, err
+	}
+	return newPartialBodyReply(bytes, reply), nil
+}
+
+func (l *Loader) loadSlowBodyStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	b, ok := def["bytesPerSecond"]
+	if !ok {
+		return nil, errors.New("`slowBody` requires `bytesPerSecond` key")
+	}
+	bytesPerSecond, ok := b.(int)
+	if !ok || bytesPerSecond <= 0 {
+		return nil, errors.New("`bytesPerSecond` must be a positive number")
+	}
+	reply, err := l.loadNestedDefinition(path, "slowBody", "reply", def)
+	if err != nil {
+		return nil, err
+	}
+	return newSlowBodyReply(bytesPerSecond, reply), nil
+}
+
+func (l *Loader) loadRandomFailureStrategy(path string, def map[interface{}]interface{}) (replyStrategy, error) {
+	p, ok := def["probability"]
+	if !ok {
+		return nil, errors.New("`randomFailure` requires `probability` key")
+	}
+	var probability float64
+	switch v := p.(type) {
+	case int:
+		probability = float64(v)
+	case float64:
+		probability = v
+	default:
+		return nil, errors.New("`probability` must be a number")
+	}
+	if probability < 0 || probability > 1 {
+		return nil, errors.New("`probability` must be between 0 and 1")
+	}
+	seed := time.Now().UnixNano()
+	if s, ok := def["seed"]; ok {
+		v, ok := s.(int)
+		if !ok {
+			return nil, errors.New("`seed` must be integer")
+		}
+		seed = int64(v)
+	}
+	reply, err := l.loadNestedDefinition(path, "randomFailure", "reply", def)
+	if err != nil {
+		return nil, err
+	}
+	var failure *definition
+	if _, ok := def["failure"]; ok {
+		if failure, err = l.loadNestedDefinition(path, "randomFailure", "failure", def); err != nil {
+			return nil, err
+		}
+	}
+	return newRandomFailureReply(probability, seed, reply, failure), nil
+}
+
 func (l *Loader) loadHeaders(def map[interface{}]interface{}) (map[string]string, error) {
 	var headers map[string]string
 	if h, ok := def["headers"]; ok {
@@ -347,6 +595,9 @@ func (l *Loader) loadConstraintOfKind(kind string, def map[interface{}]interface
 	case "bodyMatchesXML":
 		*ak = append(*ak, "body", "comparisonParams")
 		return l.loadBodyMatchesXMLConstraint(def)
+	case "stateIs":
+		*ak = append(*ak, "scenario", "state")
+		return l.loadStateIsConstraint(def)
 	default:
 		return nil, fmt.Errorf("unknown constraint: %s", kind)
 	}
@@ -550,6 +801,22 @@ func (l *Loader) loadBodyMatchesTextConstraint(def map[interface{}]interface{})
 	return newBodyMatchesTextConstraint(bodyStr, regexpStr)
 }
 
+func (l *Loader) loadStateIsConstraint(def map[interface{}]interface{}) (verifier, error) {
+	scenario, err := loadScenarioName(def)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := def["state"]
+	if !ok {
+		return nil, errors.New("`stateIs` requires `state` key")
+	}
+	state, ok := c.(string)
+	if !ok || state == "" {
+		return nil, errors.New("`state` must be string")
+	}
+	return &stateConstraint{states: l.mocks.states, scenario: scenario, state: state}, nil
+}
+
 func validateMapKeys(m map[interface{}]interface{}, allowedKeys ...string) error {
 	for k, _ := range m {
 		k := k.(string)
@@ -4,11 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
+
+	"github.com/lamoda/gonkey/models"
 )
 
 type Mocks struct {
-	mocks map[string]*ServiceMock
+	mocks  map[string]*ServiceMock
+	strict bool
+	// states of the scenarios are shared by all the mocks
+	states *scenarioStates
+
+	adminServer   *http.Server
+	adminListener net.Listener
 }
 
 func New(mocks ...*ServiceMock) *Mocks {
@@ -17,7 +27,8 @@ func New(mocks ...*ServiceMock) *Mocks {
 		mocksMap[v.ServiceName] = v
 	}
 	return &Mocks{
-		mocks: mocksMap,
+		mocks:  mocksMap,
+		states: newScenarioStates(),
 	}
 }
 
@@ -27,10 +38,37 @@ func NewNop(serviceNames ...string) *Mocks {
 		mocksMap[name] = NewServiceMock(name, newDefinition("$", nil, &failReply{}, callsNoConstraint))
 	}
 	return &Mocks{
-		mocks: mocksMap,
+		mocks:  mocksMap,
+		states: newScenarioStates(),
 	}
 }
 
+// Add registers the given service mocks, replacing ones with the same names
+func (m *Mocks) Add(mocks ...*ServiceMock) {
+	for _, v := range mocks {
+		v.strict = m.strict
+		m.mocks[v.ServiceName] = v
+	}
+}
+
+// SetStrict enables strict mode: every request which is not expected by the running test is an error.
+// The requests to the mocks not defined by the test are reported in the test,
+// the requests received between tests (after EndRunningContext and before ResetRunningContext)
+// are reported in the next test, the requests received after the last test are returned by PendingErrors.
+func (m *Mocks) SetStrict(strict bool) {
+	m.strict = strict
+	for _, v := range m.mocks {
+		v.Lock()
+		v.strict = strict
+		v.Unlock()
+	}
+}
+
+// Strict returns true if strict mode is enabled
+func (m *Mocks) Strict() bool {
+	return m.strict
+}
+
 func (m *Mocks) ResetDefinitions() {
 	for _, v := range m.mocks {
 		v.ResetDefinition()
@@ -62,6 +100,9 @@ func (m *Mocks) ShutdownContext(ctx context.Context) error {
 			errs = append(errs, fmt.Sprintf("%s: %s", v.mock.path, err.Error()))
 		}
 	}
+	if err := m.shutdownAdmin(ctx); err != nil {
+		errs = append(errs, fmt.Sprintf("admin: %s", err.Error()))
+	}
 	if len(errs) != 0 {
 		return errors.New(strings.Join(errs, "; "))
 	}
@@ -74,11 +115,17 @@ func (m *Mocks) Service(serviceName string) *ServiceMock {
 }
 
 func (m *Mocks) ResetRunningContext() {
+	m.states.reset()
 	for _, v := range m.mocks {
 		v.ResetRunningContext()
 	}
 }
 
+// State returns the current state of the scenario, see `newState` key of the mock definition
+func (m *Mocks) State(scenario string) string {
+	return m.states.get(scenario)
+}
+
 func (m *Mocks) EndRunningContext() []error {
 	var errors []error
 	for _, v := range m.mocks {
@@ -86,3 +133,24 @@ func (m *Mocks) EndRunningContext() []error {
 	}
 	return errors
 }
+
+// PendingErrors returns the errors of strict mocks which are not reported in any test yet,
+// e.g. the requests received after the last test, and forgets them
+func (m *Mocks) PendingErrors() []error {
+	var errors []error
+	for _, v := range m.mocks {
+		errors = append(errors, v.takePendingErrors()...)
+	}
+	return errors
+}
+
+// Calls returns requests received by all the mocks since the running context was reset,
+// in the order they were made
+func (m *Mocks) Calls() []models.MockCall {
+	var calls []models.MockCall
+	for _, v := range m.mocks {
+		calls = append(calls, v.Calls()...)
+	}
+	sortCalls(calls)
+	return calls
+}
@@ -0,0 +1,218 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// skippedProxyHeaders are managed by the transport and are not passed through the proxy
+var skippedProxyHeaders = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Date":              true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+}
+
+// exchange is the request to the upstream service and its response
+type exchange struct {
+	method          string
+	path            string
+	query           string
+	requestBody     string
+	statusCode      int
+	responseHeaders map[string]string
+	responseBody    string
+}
+
+// proxyReply forwards the requests to the upstream service and replies with its responses.
+// If filename is set, the exchanges of the running context are written to the file
+// as a mock definition replaying them.
+type proxyReply struct {
+	upstream *url.URL
+	client   *http.Client
+	filename string
+	replayAs string
+
+	sync.Mutex
+	exchanges []exchange
+}
+
+func newProxyReply(upstream, filename, replayAs string) (replyStrategy, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("`url` should be an absolute URL, got %s", upstream)
+	}
+	return &proxyReply{
+		upstream: u,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			// redirects are replied to the client as they are
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		filename: filename,
+		replayAs: replayAs,
+	}, nil
+}
+
+func (s *proxyReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	requestBody := readRequestBody(r)
+
+	target := *s.upstream
+	target.Path = strings.TrimRight(target.Path, "/") + "/" + strings.TrimLeft(r.URL.Path, "/")
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequest(r.Method, target.String(), strings.NewReader(requestBody))
+	if err != nil {
+		return []error{err}
+	}
+	for k, v := range r.Header {
+		if !skippedProxyHeaders[k] {
+			req.Header[k] = v
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return []error{fmt.Errorf("request to upstream %s failed: %w", s.upstream, err)}
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return []error{fmt.Errorf("reading response of upstream %s failed: %w", s.upstream, err)}
+	}
+
+	headers := make(map[string]string)
+	for k, v := range resp.Header {
+		if !skippedProxyHeaders[k] && len(v) != 0 {
+			headers[k] = v[0]
+			for _, value := range v {
+				w.Header().Add(k, value)
+			}
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+
+	s.Lock()
+	s.exchanges = append(s.exchanges, exchange{
+		method:          r.Method,
+		path:            r.URL.Path,
+		query:           r.URL.RawQuery,
+		requestBody:     requestBody,
+		statusCode:      resp.StatusCode,
+		responseHeaders: headers,
+		responseBody:    string(body),
+	})
+	s.Unlock()
+	return nil
+}
+
+func (s *proxyReply) ResetRunningContext() {
+	s.Lock()
+	defer s.Unlock()
+	s.exchanges = nil
+}
+
+func (s *proxyReply) EndRunningContext() []error {
+	s.Lock()
+	defer s.Unlock()
+	if s.filename == "" || len(s.exchanges) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(replayDefinition(s.exchanges, s.replayAs))
+	if err != nil {
+		return []error{err}
+	}
+	if err := ioutil.WriteFile(s.filename, data, 0644); err != nil {
+		return []error{fmt.Errorf("unable to write recorded exchanges: %w", err)}
+	}
+	return nil
+}
+
+// replayDefinition returns the mock definition replying with the recorded responses:
+// `sequence` checks the requests come in the recorded order,
+// `basedOnRequest` chooses the response by the request
+func replayDefinition(exchanges []exchange, replayAs string) yaml.MapSlice {
+	variants := make([]yaml.MapSlice, len(exchanges))
+	for i, e := range exchanges {
+		variants[i] = exchangeDefinition(e)
+	}
+	if replayAs == "basedOnRequest" {
+		return yaml.MapSlice{
+			{Key: "strategy", Value: "basedOnRequest"},
+			{Key: "uris", Value: variants},
+		}
+	}
+	return yaml.MapSlice{
+		{Key: "strategy", Value: "sequence"},
+		{Key: "sequence", Value: variants},
+	}
+}
+
+func exchangeDefinition(e exchange) yaml.MapSlice {
+	constraints := []yaml.MapSlice{
+		{{Key: "kind", Value: "methodIs"}, {Key: "method", Value: e.method}},
+		{{Key: "kind", Value: "pathMatches"}, {Key: "path", Value: e.path}},
+	}
+	if e.query != "" {
+		constraints = append(constraints, yaml.MapSlice{
+			{Key: "kind", Value: "queryMatches"},
+			{Key: "expectedQuery", Value: e.query},
+		})
+	}
+	if e.requestBody != "" {
+		if json.Valid([]byte(e.requestBody)) {
+			constraints = append(constraints, yaml.MapSlice{
+				{Key: "kind", Value: "bodyMatchesJSON"},
+				{Key: "body", Value: e.requestBody},
+			})
+		} else {
+			constraints = append(constraints, yaml.MapSlice{
+				{Key: "kind", Value: "bodyMatchesText"},
+				{Key: "body", Value: e.requestBody},
+			})
+		}
+	}
+
+	def := yaml.MapSlice{
+		{Key: "requestConstraints", Value: constraints},
+		{Key: "strategy", Value: "constant"},
+		{Key: "statusCode", Value: e.statusCode},
+	}
+	if len(e.responseHeaders) != 0 {
+		def = append(def, yaml.MapItem{Key: "headers", Value: e.responseHeaders})
+	}
+	return append(def, yaml.MapItem{Key: "body", Value: e.responseBody})
+}
+
+// loadReplayDefinition reads the definition written by `record` strategy
+func loadReplayDefinition(filename string) (interface{}, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var def interface{}
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", filename, err)
+	}
+	return def, nil
+}
@@ -0,0 +1,158 @@
+package mocks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func loadMocksDefinition(t *testing.T, m *Mocks, definition string) {
+	var def map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(definition), &def))
+	require.NoError(t, NewLoader(m).Load(def))
+}
+
+func doMockRequest(t *testing.T, method, url, body string) (int, string, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, resp.Header.Get("X-Stock"), string(respBody)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Stock", "main")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"reserved": %s}`, body)
+			return
+		}
+		fmt.Fprintf(w, `{"path": "%s", "query": "%s"}`, r.URL.Path, r.URL.RawQuery)
+	}))
+	defer upstream.Close()
+
+	dir, err := ioutil.TempDir("", "gonkey-mocks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, replayAs := range []string{"sequence", "basedOnRequest"} {
+		t.Run(replayAs, func(t *testing.T) {
+			filename := filepath.Join(dir, replayAs+".yaml")
+
+			m := NewNop("stock")
+			require.NoError(t, m.Start())
+			defer m.Shutdown()
+			addr := "http://" + m.Service("stock").ServerAddr()
+
+			loadMocksDefinition(t, m, fmt.Sprintf(`
+stock:
+  strategy: record
+  url: %s/api
+  filename: %s
+  replayAs: %s
+`, upstream.URL, filename, replayAs))
+			m.ResetRunningContext()
+
+			code, header, body := doMockRequest(t, http.MethodGet, addr+"/items/1?fields=qty", "")
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, "main", header)
+			require.Equal(t, `{"path": "/api/items/1", "query": "fields=qty"}`, body)
+			code, _, body = doMockRequest(t, http.MethodPost, addr+"/reserve", `{"id": 1}`)
+			require.Equal(t, http.StatusCreated, code)
+			require.Equal(t, `{"reserved": {"id": 1}}`, body)
+			require.Empty(t, m.EndRunningContext())
+
+			loadMocksDefinition(t, m, fmt.Sprintf(`
+stock:
+  strategy: replay
+  filename: %s
+`, filename))
+			m.ResetRunningContext()
+
+			code, header, body = doMockRequest(t, http.MethodGet, addr+"/items/1?fields=qty", "")
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, "main", header)
+			require.Equal(t, `{"path": "/api/items/1", "query": "fields=qty"}`, body)
+			code, _, body = doMockRequest(t, http.MethodPost, addr+"/reserve", `{"id": 1}`)
+			require.Equal(t, http.StatusCreated, code)
+			require.Equal(t, `{"reserved": {"id": 1}}`, body)
+			require.Empty(t, m.EndRunningContext())
+
+			m.ResetRunningContext()
+			doMockRequest(t, http.MethodPost, addr+"/reserve", `{"id": 2}`)
+			require.NotEmpty(t, m.EndRunningContext())
+		})
+	}
+}
+
+func TestProxyUpstreamFailure(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstreamURL := upstream.URL
+	upstream.Close()
+
+	m := NewNop("stock")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	loadMocksDefinition(t, m, fmt.Sprintf(`
+stock:
+  strategy: proxy
+  url: %s
+`, upstreamURL))
+	m.ResetRunningContext()
+
+	code, _, _ := doMockRequest(t, http.MethodGet, "http://"+m.Service("stock").ServerAddr()+"/items", "")
+	require.Equal(t, http.StatusBadGateway, code)
+	require.Len(t, m.EndRunningContext(), 1)
+}
+
+func TestProxyDoesNotBlockMock(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(time.Second)
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	m := NewNop("stock")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+	loadMocksDefinition(t, m, fmt.Sprintf(`
+stock:
+  strategy: proxy
+  url: %s
+`, upstream.URL))
+	m.ResetRunningContext()
+	addr := "http://" + m.Service("stock").ServerAddr()
+
+	slowDone := make(chan string)
+	go func() {
+		_, _, body := doMockRequest(t, http.MethodGet, addr+"/slow", "")
+		slowDone <- body
+	}()
+	// let the slow request reach the upstream
+	time.Sleep(100 * time.Millisecond)
+
+	startedAt := time.Now()
+	_, _, body := doMockRequest(t, http.MethodGet, addr+"/fast", "")
+	require.Equal(t, "/fast", body)
+	require.True(t, time.Since(startedAt) < 500*time.Millisecond)
+
+	require.Equal(t, "/slow", <-slowDone)
+	require.Empty(t, m.EndRunningContext())
+}
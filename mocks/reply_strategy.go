@@ -32,6 +32,15 @@ func unhandledRequestError(r *http.Request) []error {
 	return []error{fmt.Errorf("unhandled request to mock:\n%s", requestContent)}
 }
 
+// unexpectedRequestError reports the request which is not expected in strict mode, whatever the mock replies
+func unexpectedRequestError(reason string, r *http.Request) error {
+	requestContent, err := httputil.DumpRequest(r, true)
+	if err != nil {
+		return fmt.Errorf("Gonkey internal error during request dump: %s\n", err)
+	}
+	return fmt.Errorf("unexpected request to mock, %s:\n%s", reason, requestContent)
+}
+
 func newFileReplyWithCode(filename string, statusCode int, headers map[string]string) (replyStrategy, error) {
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -174,14 +183,16 @@ func (s *sequentialReply) EndRunningContext() []error {
 }
 
 func (s *sequentialReply) HandleRequest(w http.ResponseWriter, r *http.Request) []error {
+	// the lock is held only to take the next reply, so a slow reply doesn't delay the following ones
 	s.Lock()
-	defer s.Unlock()
 	// out of bounds, url requested more times than sequence length
 	if s.count >= len(s.sequence) {
+		s.Unlock()
 		return unhandledRequestError(r)
 	}
 	def := s.sequence[s.count]
 	s.count++
+	s.Unlock()
 	return def.Execute(w, r)
 }
 
@@ -202,10 +213,16 @@ func (s *basedOnRequestReply) HandleRequest(w http.ResponseWriter, r *http.Reque
 
 	var errors []error
 	for _, def := range s.variants {
+		unlock := def.lockScenarios()
 		errs := verifyRequestConstraints(def.requestConstraints, r)
 		if errs == nil {
+			if def.transition != nil {
+				def.transition.apply()
+			}
+			unlock()
 			return def.ExecuteWithoutVerifying(w, r)
 		}
+		unlock()
 		errors = append(errors, errs...)
 	}
 	return append(errors, unhandledRequestError(r)...)
@@ -0,0 +1,126 @@
+package mocks
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	// defaultScenario is used by the definitions and constraints which don't name the scenario
+	defaultScenario = "default"
+	// initialState is the state of every scenario at the beginning of the test
+	initialState = "Started"
+)
+
+// scenarioStates are the states of the scenarios shared by all the mocks during the test
+type scenarioStates struct {
+	sync.Mutex
+	states map[string]string
+	// locks of the scenarios make the check of the state and the transition atomic
+	locks map[string]*sync.Mutex
+}
+
+func newScenarioStates() *scenarioStates {
+	return &scenarioStates{
+		states: make(map[string]string),
+		locks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// lock locks the scenarios until the returned function is called,
+// the scenarios are locked in the order of their names, so the locks don't deadlock
+func (s *scenarioStates) lock(scenarios []string) (unlock func()) {
+	sort.Strings(scenarios)
+
+	var locks []*sync.Mutex
+	s.Lock()
+	for i, scenario := range scenarios {
+		if i > 0 && scenario == scenarios[i-1] {
+			continue
+		}
+		l, ok := s.locks[scenario]
+		if !ok {
+			l = &sync.Mutex{}
+			s.locks[scenario] = l
+		}
+		locks = append(locks, l)
+	}
+	s.Unlock()
+
+	for _, l := range locks {
+		l.Lock()
+	}
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+	}
+}
+
+func (s *scenarioStates) get(scenario string) string {
+	s.Lock()
+	defer s.Unlock()
+	if state, ok := s.states[scenario]; ok {
+		return state
+	}
+	return initialState
+}
+
+func (s *scenarioStates) set(scenario, state string) {
+	s.Lock()
+	defer s.Unlock()
+	s.states[scenario] = state
+}
+
+func (s *scenarioStates) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.states = make(map[string]string)
+}
+
+// stateConstraint requires the scenario to be in the state
+type stateConstraint struct {
+	states   *scenarioStates
+	scenario string
+	state    string
+}
+
+func (c *stateConstraint) Verify(r *http.Request) []error {
+	if actual := c.states.get(c.scenario); actual != c.state {
+		return []error{fmt.Errorf("scenario %s state does not match: expected %s, actual %s", c.scenario, c.state, actual)}
+	}
+	return nil
+}
+
+// stateTransition moves the scenario to the new state when the definition replies
+type stateTransition struct {
+	states   *scenarioStates
+	scenario string
+	state    string
+}
+
+func (t *stateTransition) apply() {
+	t.states.set(t.scenario, t.state)
+}
+
+// lockScenarios locks the scenarios checked by the constraints of the definition or changed by its transition
+func (d *definition) lockScenarios() (unlock func()) {
+	var states *scenarioStates
+	var scenarios []string
+	for _, c := range d.requestConstraints {
+		if sc, ok := c.(*stateConstraint); ok {
+			states = sc.states
+			scenarios = append(scenarios, sc.scenario)
+		}
+	}
+	if d.transition != nil {
+		states = d.transition.states
+		scenarios = append(scenarios, d.transition.scenario)
+	}
+	if states == nil {
+		return func() {}
+	}
+	return states.lock(scenarios)
+}
@@ -0,0 +1,131 @@
+package mocks
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const scenarioMockDefinition = `
+orders:
+  strategy: basedOnRequest
+  uris:
+    - requestConstraints:
+        - kind: methodIs
+          method: POST
+      strategy: constant
+      body: '{"id": 1}'
+      scenario: order
+      newState: created
+    - requestConstraints:
+        - kind: methodIs
+          method: DELETE
+        - kind: stateIs
+          scenario: order
+          state: created
+      strategy: nop
+      scenario: order
+      newState: deleted
+catalog:
+  strategy: basedOnRequest
+  uris:
+    - requestConstraints:
+        - kind: stateIs
+          scenario: order
+          state: created
+      strategy: constant
+      body: '{"id": 1, "status": "created"}'
+    - requestConstraints:
+        - kind: stateIs
+          scenario: order
+          state: Started
+      strategy: constant
+      body: ''
+      statusCode: 404
+`
+
+func TestScenarioStates(t *testing.T) {
+	m := NewNop("orders", "catalog")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	orders := "http://" + m.Service("orders").ServerAddr()
+	catalog := "http://" + m.Service("catalog").ServerAddr()
+
+	loadMocksDefinition(t, m, scenarioMockDefinition)
+	m.ResetRunningContext()
+
+	code, _, _ := doMockRequest(t, http.MethodGet, catalog+"/orders/1", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	code, _, _ = doMockRequest(t, http.MethodDelete, orders+"/orders/1", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "Started", m.State("order"))
+
+	_, _, body := doMockRequest(t, http.MethodPost, orders+"/orders", `{"sku": "a1"}`)
+	require.Equal(t, `{"id": 1}`, body)
+	require.Equal(t, "created", m.State("order"))
+
+	_, _, body = doMockRequest(t, http.MethodGet, catalog+"/orders/1", "")
+	require.Equal(t, `{"id": 1, "status": "created"}`, body)
+
+	code, _, _ = doMockRequest(t, http.MethodDelete, orders+"/orders/1", "")
+	require.Equal(t, http.StatusNoContent, code)
+	require.Equal(t, "deleted", m.State("order"))
+
+	// the delete before the order was created is unexpected
+	require.NotEmpty(t, m.EndRunningContext())
+
+	m.ResetRunningContext()
+	require.Equal(t, "Started", m.State("order"))
+}
+
+func TestScenarioStateIsChangedOnceByConcurrentRequests(t *testing.T) {
+	m := NewNop("orders")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	// both definitions require the initial state and change it, so only one of them may reply
+	loadMocksDefinition(t, m, `
+orders:
+  strategy: uriVary
+  uris:
+    /a:
+      requestConstraints:
+        - kind: stateIs
+          state: Started
+      strategy: delay
+      duration: 100
+      reply:
+        strategy: constant
+        body: a
+      newState: created
+    /b:
+      requestConstraints:
+        - kind: stateIs
+          state: Started
+      strategy: delay
+      duration: 100
+      reply:
+        strategy: constant
+        body: b
+      newState: created
+`)
+	m.ResetRunningContext()
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/a", "/b"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			doMockRequest(t, http.MethodPost, "http://"+m.Service("orders").ServerAddr()+path, "")
+		}(path)
+	}
+	wg.Wait()
+
+	errs := m.EndRunningContext()
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "scenario default state does not match: expected Started, actual created")
+}
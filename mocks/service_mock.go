@@ -5,15 +5,32 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/lamoda/gonkey/models"
 )
 
 type ServiceMock struct {
 	server            *http.Server
+	grpcServer        *grpc.Server
+	grpcResolver      GrpcMethodResolver
 	listener          net.Listener
 	mock              *definition
 	defaultDefinition *definition
 	sync.RWMutex
 	errors []error
+	calls  []models.MockCall
+
+	// strict mock reports every request which is not expected by the running test
+	strict  bool
+	running bool
+	// generation is the number of the running context, the request replied after the context has changed
+	// doesn't belong to the running test
+	generation int
+	// errors of the requests received between tests, they are reported in the next running context
+	pendingErrors []error
 
 	ServiceName string
 }
@@ -31,7 +48,11 @@ func (m *ServiceMock) StartServer() error {
 }
 
 func (m *ServiceMock) StartServerWithAddr(addr string) error {
-	ln, err := net.Listen("tcp", addr)
+	if m.grpcResolver != nil {
+		return m.startGrpcServer(addr)
+	}
+
+	ln, err := listen(addr)
 	if err != nil {
 		return err
 	}
@@ -41,7 +62,15 @@ func (m *ServiceMock) StartServerWithAddr(addr string) error {
 	return nil
 }
 
+func listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
 func (m *ServiceMock) ShutdownServer(ctx context.Context) error {
+	if m.grpcServer != nil {
+		return m.shutdownGrpcServer(ctx)
+	}
+
 	err := m.server.Shutdown(ctx)
 	m.listener = nil
 	m.server = nil
@@ -56,13 +85,65 @@ func (m *ServiceMock) ServerAddr() string {
 }
 
 func (m *ServiceMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	startedAt := time.Now()
+	requestBody := readRequestBody(r)
+	recorder := newCallRecorder(w)
+
+	// the reply is made without the lock, so a slow reply doesn't block the other requests to the mock;
+	// the strategies keeping state between requests (sequence, basedOnRequest, proxy and record,
+	// randomFailure, scenario states and the calls of the definitions) guard it with their own locks
+	m.RLock()
+	mock, strict, running, generation := m.mock, m.strict, m.running, m.generation
+	m.RUnlock()
+
+	var unexpected error
+	if strict {
+		switch {
+		case !running:
+			unexpected = unexpectedRequestError("received between tests", r)
+		case mock == m.defaultDefinition:
+			unexpected = unexpectedRequestError("the mock is not defined by the test", r)
+		}
+	}
+
+	var errs []error
+	if mock != nil {
+		errs = mock.Execute(recorder, r)
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
-	if m.mock != nil {
-		errs := m.mock.Execute(w, r)
+	stale := m.generation != generation
+	if strict && (!running || !m.running || stale) {
+		// the definition belongs to the previous test, only the request itself is reported
+		if unexpected == nil {
+			unexpected = unexpectedRequestError("replied after the end of the test", r)
+		}
+		m.pendingErrors = append(m.pendingErrors, unexpected)
+		return
+	}
+	if stale {
+		// the running context was reset during the reply, the request doesn't belong to the running test
+		return
+	}
+	if unexpected != nil {
+		m.errors = append(m.errors, unexpected)
+	} else {
 		m.errors = append(m.errors, errs...)
 	}
+
+	m.calls = append(m.calls, newMockCall(m.ServiceName, r, requestBody, recorder, startedAt))
+}
+
+// Calls returns requests received by the mock since the running context was reset
+func (m *ServiceMock) Calls() []models.MockCall {
+	m.RLock()
+	defer m.RUnlock()
+
+	calls := make([]models.MockCall, len(m.calls))
+	copy(calls, m.calls)
+	return calls
 }
 
 func (m *ServiceMock) SetDefinition(newDefinition *definition) {
@@ -80,14 +161,36 @@ func (m *ServiceMock) ResetDefinition() {
 func (m *ServiceMock) ResetRunningContext() {
 	m.Lock()
 	defer m.Unlock()
-	m.errors = nil
+	m.errors = m.pendingErrors
+	m.pendingErrors = nil
+	m.calls = nil
+	m.running = true
+	m.generation++
 	m.mock.ResetRunningContext()
 }
 
+// takePendingErrors returns the errors of the requests received since the running context was ended
+// and forgets them, so they are not reported in the next running context
+func (m *ServiceMock) takePendingErrors() []error {
+	m.Lock()
+	defer m.Unlock()
+
+	errs := m.pendingErrors
+	m.pendingErrors = nil
+	for i, e := range errs {
+		errs[i] = &Error{
+			error:       e,
+			ServiceName: m.ServiceName,
+		}
+	}
+	return errs
+}
+
 func (m *ServiceMock) EndRunningContext() []error {
-	m.RLock()
-	defer m.RUnlock()
+	m.Lock()
+	defer m.Unlock()
 
+	m.running = false
 	errs := append(m.errors, m.mock.EndRunningContext()...)
 	for i, e := range errs {
 		errs[i] = &Error{
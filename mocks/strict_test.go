@@ -0,0 +1,83 @@
+package mocks
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictMocks(t *testing.T) {
+	m := NewNop("orders", "stock")
+	m.SetStrict(true)
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	orders := "http://" + m.Service("orders").ServerAddr()
+	stock := "http://" + m.Service("stock").ServerAddr()
+
+	m.ResetRunningContext()
+	loadMocksDefinition(t, m, `
+orders:
+  strategy: nop
+`)
+	doMockRequest(t, http.MethodGet, orders+"/orders", "")
+	require.Empty(t, m.EndRunningContext())
+
+	// the request of the previous test arrives late
+	doMockRequest(t, http.MethodGet, orders+"/orders/late", "")
+
+	m.ResetDefinitions()
+	m.ResetRunningContext()
+	doMockRequest(t, http.MethodPost, stock+"/reserve", `{"sku": "a1"}`)
+	errs := m.EndRunningContext()
+	require.Len(t, errs, 2)
+	// the order of the services is not defined
+	messages := errs[0].Error() + "\n" + errs[1].Error()
+	require.Contains(t, messages, "mock orders: unexpected request to mock, received between tests:\nGET /orders/late")
+	require.Contains(t, messages, "mock stock: unexpected request to mock, the mock is not defined by the test:\nPOST /reserve")
+	require.Contains(t, messages, `{"sku": "a1"}`)
+
+	m.ResetRunningContext()
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestNotStrictMocks(t *testing.T) {
+	m := NewNop("orders")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	orders := "http://" + m.Service("orders").ServerAddr()
+
+	m.ResetRunningContext()
+	loadMocksDefinition(t, m, `
+orders:
+  strategy: nop
+`)
+	require.Empty(t, m.EndRunningContext())
+
+	doMockRequest(t, http.MethodGet, orders+"/orders/late", "")
+
+	m.ResetRunningContext()
+	require.Empty(t, m.EndRunningContext())
+}
+
+func TestStrictMocksRequestAfterLastTest(t *testing.T) {
+	m := NewNop("orders")
+	m.SetStrict(true)
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	orders := "http://" + m.Service("orders").ServerAddr()
+
+	m.ResetRunningContext()
+	require.Empty(t, m.EndRunningContext())
+
+	// the request of the last test arrives late
+	doMockRequest(t, http.MethodGet, orders+"/orders/late", "")
+
+	errs := m.PendingErrors()
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "mock orders: unexpected request to mock, received between tests:\nGET /orders/late")
+	require.Empty(t, m.PendingErrors())
+}
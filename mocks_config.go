@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/variables"
+)
+
+// mocksConfig declares the mocks started by the CLI, it's read from the file given in -mocks flag
+// or in -config flag of mock-server command
+type mocksConfig struct {
+	// Services maps the names of the mocks to the addresses they listen on, a random port is used for empty address
+	Services map[string]string `yaml:"services"`
+	// Strict enables strict mode of the mocks
+	Strict bool `yaml:"strict"`
+	// Admin is the address of the admin API, it's not started if empty
+	Admin string `yaml:"admin"`
+}
+
+func loadMocksConfig(path string) (*mocksConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg mocksConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse mocks config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// startMocks starts the mocks of the config and prints their addresses
+func startMocks(cfg *mocksConfig) (*mocks.Mocks, error) {
+	if len(cfg.Services) == 0 {
+		return nil, fmt.Errorf("names of the mocks are not provided")
+	}
+
+	names := make([]string, 0, len(cfg.Services))
+	for name := range cfg.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := mocks.NewNop(names...)
+	m.SetStrict(cfg.Strict)
+	for _, name := range names {
+		addr := cfg.Services[name]
+		if addr == "" {
+			addr = "localhost:0"
+		}
+		if err := m.Service(name).StartServerWithAddr(addr); err != nil {
+			return nil, fmt.Errorf("unable to start mock %s: %w", name, err)
+		}
+		log.Printf("Mock %s listens on %s", name, mockAddr(m.Service(name)))
+	}
+
+	if cfg.Admin != "" {
+		if err := m.StartAdmin(cfg.Admin); err != nil {
+			return nil, fmt.Errorf("unable to start admin API: %w", err)
+		}
+		log.Printf("Admin API listens on %s", m.AdminAddr())
+	}
+	return m, nil
+}
+
+// mockAddr returns the address of the mock to connect to,
+// the mock listening on all interfaces is reached via localhost
+func mockAddr(m *mocks.ServiceMock) string {
+	addr := m.ServerAddr()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return net.JoinHostPort("localhost", port)
+	}
+	return addr
+}
+
+// setMockAddrVariables sets mock_addr_<service> variables to the addresses of the mocks
+func setMockAddrVariables(vars *variables.Variables, m *mocks.Mocks, cfg *mocksConfig) {
+	for name := range cfg.Services {
+		vars.Set("mock_addr_"+name, mockAddr(m.Service(name)))
+	}
+}
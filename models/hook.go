@@ -0,0 +1,36 @@
+package models
+
+// Hook is an action made before or after the tests: an HTTP request to the tested service,
+// an SQL statement, loading of fixtures or a script. Only one of the actions is made by a hook.
+type Hook struct {
+	Name     string       `json:"name" yaml:"name"`
+	Request  *HookRequest `json:"request" yaml:"request"`
+	DbExec   string       `json:"dbExec" yaml:"dbExec"`
+	Fixtures []string     `json:"fixtures" yaml:"fixtures"`
+	Script   *HookScript  `json:"script" yaml:"script"`
+	// VariablesToSet maps names of the variables to paths in JSON response of the request,
+	// or to columns of the first row returned by the SQL statement
+	VariablesToSet map[string]string `json:"variables_to_set" yaml:"variables_to_set"`
+}
+
+// HookRequest is an HTTP request made by the hook
+type HookRequest struct {
+	Method  string            `json:"method" yaml:"method"`
+	Path    string            `json:"path" yaml:"path"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Body    string            `json:"body" yaml:"body"`
+	// Status is the expected status code of the response, any 2xx status if not set
+	Status int `json:"status" yaml:"status"`
+}
+
+// HookScript is a script run by the hook
+type HookScript struct {
+	Path    string `json:"path" yaml:"path"`
+	Timeout int    `json:"timeout" yaml:"timeout"`
+}
+
+// Hooks made before the first and after the last test of a file or of the whole run
+type Hooks struct {
+	BeforeAll []Hook `json:"beforeAll" yaml:"beforeAll"`
+	AfterAll  []Hook `json:"afterAll" yaml:"afterAll"`
+}
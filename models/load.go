@@ -0,0 +1,56 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// LoadSummary is the result of the load test
+type LoadSummary struct {
+	Duration time.Duration
+	Requests int
+	// Errors is the number of requests which failed or didn't pass the checks
+	Errors int
+	// Dropped is the number of requests which were not sent at the requested rate
+	// because all the workers were busy
+	Dropped     int
+	StatusCodes map[int]int
+	Latencies   []time.Duration
+}
+
+// RPS returns the achieved rate of the requests per second
+func (s *LoadSummary) RPS() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / s.Duration.Seconds()
+}
+
+// ErrorRate returns the share of failed requests in percents
+func (s *LoadSummary) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Requests)
+}
+
+// Percentile returns the latency which is not exceeded by p percents of the requests
+func (s *LoadSummary) Percentile(p float64) time.Duration {
+	if len(s.Latencies) == 0 {
+		return 0
+	}
+	latencies := make([]time.Duration, len(s.Latencies))
+	copy(latencies, s.Latencies)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	// nearest-rank method
+	idx := int(math.Ceil(float64(len(latencies))*p/100)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(latencies) {
+		idx = len(latencies) - 1
+	}
+	return latencies[idx]
+}
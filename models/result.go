@@ -1,12 +1,58 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
 
 type DatabaseResult struct {
 	Query    string
 	Response []string
 }
 
+// Attempt of sending the request, recorded when the request is repeated
+type Attempt struct {
+	ResponseStatusCode int
+	ResponseBody       string
+	Errors             []error
+}
+
+// MockCall is a request received by the service mock during the test
+type MockCall struct {
+	Service      string
+	Method       string
+	Path         string
+	Query        string
+	Headers      http.Header
+	RequestBody  string
+	StatusCode   int
+	ResponseBody string
+	Time         time.Time
+}
+
+// Timings of the request, the phases which didn't happen
+// (e.g. DNS lookup for IP address or TLS handshake for plain HTTP) are zero
+type Timings struct {
+	DNSLookup       time.Duration
+	Connect         time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration
+	Total           time.Duration
+}
+
+func (t Timings) String() string {
+	return fmt.Sprintf(
+		"%s (dns %s, connect %s, tls %s, ttfb %s)",
+		t.Total.Round(time.Microsecond),
+		t.DNSLookup.Round(time.Microsecond),
+		t.Connect.Round(time.Microsecond),
+		t.TLSHandshake.Round(time.Microsecond),
+		t.TimeToFirstByte.Round(time.Microsecond),
+	)
+}
+
 // Result of test execution
 type Result struct {
 	Path                string // TODO: remove
@@ -17,9 +63,19 @@ type Result struct {
 	ResponseContentType string
 	ResponseBody        string
 	ResponseHeaders     map[string][]string
+	Timings             Timings
 	Errors              []error
 	Test                TestInterface
 	DatabaseResult      []DatabaseResult
+	Attempts            []Attempt
+	// Stderr of the script for the tests with `script` section
+	Stderr string
+	// Steps contains results of the scenario steps in the order of execution
+	Steps []*Result
+	// MockCalls contains requests received by the mocks during the test
+	MockCalls []MockCall
+	// Duration of the test execution, including fixtures loading and scripts
+	Duration time.Duration
 }
 
 func allureStatus(status string) bool {
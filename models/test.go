@@ -1,5 +1,12 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lamoda/gonkey/compare"
+)
+
 type DatabaseCheck interface {
 	DbQueryString() string
 	DbResponseJson() []string
@@ -8,6 +15,24 @@ type DatabaseCheck interface {
 	SetDbResponseJson([]string)
 }
 
+// MongoDatabaseCheck is a database check which can query MongoDB instead of SQL database
+type MongoDatabaseCheck interface {
+	DatabaseCheck
+
+	// GetMongoQuery returns the query to MongoDB, nil for SQL checks
+	GetMongoQuery() *MongoQuery
+	SetMongoQuery(*MongoQuery)
+}
+
+// MongoQuery finds documents of the collection or aggregates them,
+// the filter, sort and pipeline are given in MongoDB extended JSON
+type MongoQuery struct {
+	Collection string `json:"collection" yaml:"collection"`
+	Find       string `json:"find" yaml:"find"`
+	Sort       string `json:"sort" yaml:"sort"`
+	Aggregate  string `json:"aggregate" yaml:"aggregate"`
+}
+
 // Common Test interface
 type TestInterface interface {
 	ToQuery() string
@@ -22,8 +47,19 @@ type TestInterface interface {
 	GetStatus() string
 	SetStatus(string)
 	Fixtures() []string
+	// BrokerFixtures returns fixtures with messages published to the message broker before the request
+	BrokerFixtures() []string
 	ServiceMocks() map[string]interface{}
 	Pause() int
+	RequestTimeout() int
+	// MaxResponseTime returns the response time in milliseconds which fails the test when exceeded, 0 if not limited
+	MaxResponseTime() int
+	GetRetryParams() RetryParams
+	// GetPollCondition returns the test with expectations which must be met
+	// before the request stops being repeated, nil if polling is not used
+	GetPollCondition() TestInterface
+	IsolationGroup() string
+	Parallel() bool
 	BeforeScriptPath() string
 	BeforeScriptTimeout() int
 	AfterRequestScriptPath() string
@@ -32,14 +68,36 @@ type TestInterface interface {
 	Headers() map[string]string
 	ContentType() string
 	GetForm() *Form
+	// GetGrpcRequest returns the gRPC call made instead of the HTTP request, nil for HTTP tests
+	GetGrpcRequest() *GrpcRequest
+	// GetScript returns the script run instead of the HTTP request, nil for HTTP tests
+	GetScript() *ScriptRequest
+	// GetExpectedStderr returns stderr expected from the script, false if it's not checked
+	GetExpectedStderr() (string, bool)
 	DbQueryString() string
 	DbResponseJson() []string
 	GetVariables() map[string]string
 	GetVariablesToSet() map[int]map[string]string
 	GetDatabaseChecks() []DatabaseCheck
 	SetDatabaseChecks([]DatabaseCheck)
+	// GetBrokerChecks returns messages expected in topics of the message broker after the request
+	GetBrokerChecks() []BrokerCheck
+	SetBrokerChecks([]BrokerCheck)
+	// GetMockCallChecks returns requests expected to be received by the service mocks during the test
+	GetMockCallChecks() []MockCallCheck
+	SetMockCallChecks([]MockCallCheck)
+	// MockCallsInOrder returns true if the expected mock requests must be received in the given order
+	// across all services, otherwise only the order of the requests to the same service is checked
+	MockCallsInOrder() bool
 
 	GetFileName() string
+	// GetFileHooks returns hooks of the file the test is defined in, the same for all tests of the file,
+	// nil if the file has no hooks
+	GetFileHooks() *Hooks
+	// GetAfterTestHooks returns hooks made after the test
+	GetAfterTestHooks() []Hook
+	// GetSteps returns steps of the scenario, which are executed instead of the request of the test
+	GetSteps() []TestInterface
 
 	// setters
 	SetQuery(string)
@@ -47,6 +105,9 @@ type TestInterface interface {
 	SetPath(string)
 	SetRequest(string)
 	SetForm(form *Form)
+	SetGrpcRequest(*GrpcRequest)
+	SetScript(*ScriptRequest)
+	SetExpectedStderr(string)
 	SetResponses(map[int]string)
 	SetHeaders(map[string]string)
 	SetDbQueryString(string)
@@ -67,10 +128,89 @@ type Form struct {
 	Files map[string]string `json:"files" yaml:"files"`
 }
 
+// GrpcRequest is a unary gRPC call, the message is given in JSON
+type GrpcRequest struct {
+	Service  string            `json:"service" yaml:"service"`
+	Method   string            `json:"method" yaml:"method"`
+	Message  string            `json:"message" yaml:"message"`
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+}
+
+// ScriptRequest is a script run as the subject of the test. Its exit code is used as the response code
+// and its stdout as the response body.
+type ScriptRequest struct {
+	Path string            `json:"path" yaml:"path"`
+	Args []string          `json:"args" yaml:"args"`
+	Env  map[string]string `json:"env" yaml:"env"`
+	// Timeout in seconds, 3 by default
+	Timeout int `json:"timeout" yaml:"timeout"`
+}
+
+// BrokerMessage is a message of the message broker, the value is compared as JSON when it is valid JSON
+type BrokerMessage struct {
+	Key     string            `json:"key" yaml:"key"`
+	Value   string            `json:"value" yaml:"value"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+}
+
+// BrokerCheck defines messages which must be published to the topic during the test
+type BrokerCheck struct {
+	Topic            string                `json:"topic" yaml:"topic"`
+	Messages         []BrokerMessage       `json:"messages" yaml:"messages"`
+	ComparisonParams compare.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+// MockCallCheck is a request expected to be received by the service mock, empty fields are not checked.
+// The body is compared as JSON when it is valid JSON.
+type MockCallCheck struct {
+	Service          string                `json:"service" yaml:"service"`
+	Method           string                `json:"method" yaml:"method"`
+	Path             string                `json:"path" yaml:"path"`
+	Query            string                `json:"query" yaml:"query"`
+	Headers          map[string]string     `json:"headers" yaml:"headers"`
+	Body             string                `json:"body" yaml:"body"`
+	ComparisonParams compare.CompareParams `json:"comparisonParams" yaml:"comparisonParams"`
+}
+
+// RetryParams defines how the request of the test is repeated
+type RetryParams struct {
+	Attempts int     `json:"attempts" yaml:"attempts"`
+	Delay    int     `json:"delay" yaml:"delay"`
+	Backoff  float64 `json:"backoff" yaml:"backoff"`
+	// RetryOn lists HTTP status codes (e.g. 503) or their classes (e.g. 5xx) of the responses which are repeated
+	RetryOn []string `json:"retryOn" yaml:"retryOn"`
+}
+
+// RetriesStatus reports whether the response with the status code is repeated
+func (p RetryParams) RetriesStatus(code int) bool {
+	status := strconv.Itoa(code)
+	for _, s := range p.RetryOn {
+		s = strings.ToLower(s)
+		if s == status || strings.HasSuffix(s, "xx") && len(status) == 3 && s[0] == status[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRetryStatus reports whether the value of RetryOn is a status code or a class of codes
+func ValidRetryStatus(s string) bool {
+	if len(s) != 3 || s[0] < '1' || s[0] > '5' {
+		return false
+	}
+	if strings.ToLower(s[1:]) == "xx" {
+		return true
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
 type Summary struct {
 	Success bool
 	Failed  int
 	Skipped int
 	Broken  int
 	Total   int
+	// Errors don't belong to any test, e.g. requests to strict mocks received after the last test
+	Errors []error
 }
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/output/allure_report/beans"
 )
 
 type AllureReportOutput struct {
@@ -32,16 +34,36 @@ func NewOutput(suiteName, reportLocation string) *AllureReportOutput {
 }
 
 func (o *AllureReportOutput) Process(t models.TestInterface, result *models.Result) error {
-	testCase := o.allure.StartCase(t.GetName(), time.Now())
+	end := time.Now()
+	testCase := o.allure.StartCase(t.GetName(), end.Add(-result.Duration))
 	testCase.AddLabel("story", result.Path)
-	o.allure.AddAttachment(
-		*bytes.NewBufferString("Request"),
-		*bytes.NewBufferString(fmt.Sprintf(`Query: %s \n Body: %s`, result.Query, result.RequestBody)),
-		"txt")
-	o.allure.AddAttachment(
-		*bytes.NewBufferString("Response"),
-		*bytes.NewBufferString(fmt.Sprintf(`Body: %s`, result.ResponseBody)),
-		"txt")
+	if len(result.Steps) == 0 {
+		o.addExchangeAttachments("", result)
+		addTimingsParameters(testCase, "", result.Timings)
+	}
+
+	for i, stepResult := range result.Steps {
+		stepStatus := "passed"
+		if !stepResult.Passed() {
+			stepStatus = "failed"
+		}
+		stepEnd := time.Now()
+		step := beans.NewStep(stepResult.Test.GetName(), stepEnd.Add(-stepResult.Timings.Total))
+		step.End(stepStatus, stepEnd)
+		testCase.AddStep(step)
+
+		prefix := fmt.Sprintf("Step #%d ", i+1)
+		o.addExchangeAttachments(prefix, stepResult)
+		addTimingsParameters(testCase, prefix, stepResult.Timings)
+	}
+
+	if len(result.Attempts) != 0 {
+		testCase.SetDescription(fmt.Sprintf("Attempts: %d", len(result.Attempts)))
+		o.allure.AddAttachment(
+			*bytes.NewBufferString("Attempts"),
+			*bytes.NewBufferString(renderAttempts(result.Attempts)),
+			"txt")
+	}
 
 	for i, dbresult := range result.DatabaseResult {
 		if dbresult.Query != "" {
@@ -57,11 +79,50 @@ func (o *AllureReportOutput) Process(t models.TestInterface, result *models.Resu
 	}
 
 	status, err := result.AllureStatus()
-	o.allure.EndCase(status, err, time.Now())
+	o.allure.EndCase(status, err, end)
 
 	return nil
 }
 
+func (o *AllureReportOutput) addExchangeAttachments(prefix string, result *models.Result) {
+	o.allure.AddAttachment(
+		*bytes.NewBufferString(prefix + "Request"),
+		*bytes.NewBufferString(fmt.Sprintf(`Query: %s \n Body: %s`, result.Query, result.RequestBody)),
+		"txt")
+	o.allure.AddAttachment(
+		*bytes.NewBufferString(prefix + "Response"),
+		*bytes.NewBufferString(fmt.Sprintf(`Body: %s`, result.ResponseBody)),
+		"txt")
+	if result.Stderr != "" {
+		o.allure.AddAttachment(
+			*bytes.NewBufferString(prefix + "Stderr"),
+			*bytes.NewBufferString(result.Stderr),
+			"txt")
+	}
+}
+
+func addTimingsParameters(testCase *beans.TestCase, prefix string, timings models.Timings) {
+	if timings.Total == 0 {
+		return
+	}
+	testCase.AddParameter(prefix+"Response time", timings.Total.String())
+	testCase.AddParameter(prefix+"DNS lookup", timings.DNSLookup.String())
+	testCase.AddParameter(prefix+"Connect", timings.Connect.String())
+	testCase.AddParameter(prefix+"TLS handshake", timings.TLSHandshake.String())
+	testCase.AddParameter(prefix+"Time to first byte", timings.TimeToFirstByte.String())
+}
+
+func renderAttempts(attempts []models.Attempt) string {
+	var b strings.Builder
+	for i, a := range attempts {
+		fmt.Fprintf(&b, "Attempt #%d\n Status: %d \n Body: %s\n", i+1, a.ResponseStatusCode, a.ResponseBody)
+		for _, err := range a.Errors {
+			fmt.Fprintf(&b, " Error: %s\n", err)
+		}
+	}
+	return b.String()
+}
+
 func (o *AllureReportOutput) Finalize() {
 	o.allure.EndSuite(time.Now())
 }
@@ -30,6 +30,9 @@ type TestCase struct {
 	Labels struct {
 		Label []*Label `xml:"label"`
 	} `xml:"labels"`
+	Parameters struct {
+		Parameter []*Parameter `xml:"parameter"`
+	} `xml:"parameters"`
 	Attachments struct {
 		Attachment []*Attachment `xml:"attachment"`
 	} `xml:"attachments"`
@@ -45,6 +48,12 @@ type Label struct {
 	Value string `xml:"value,attr"`
 }
 
+type Parameter struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	Kind  string `xml:"kind,attr"`
+}
+
 func (t *TestCase) SetDescription(desc string) {
 	t.Desc = desc
 }
@@ -56,6 +65,14 @@ func (t *TestCase) AddLabel(name, value string) {
 	})
 }
 
+func (t *TestCase) AddParameter(name, value string) {
+	t.Parameters.Parameter = append(t.Parameters.Parameter, &Parameter{
+		Name:  name,
+		Value: value,
+		Kind:  "argument",
+	})
+}
+
 func (t *TestCase) AddStep(step *Step) {
 	t.Steps.Steps = append(t.Steps.Steps, step)
 }
@@ -2,7 +2,9 @@ package console_colored
 
 import (
 	"bytes"
+	"sort"
 	"text/template"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/lamoda/gonkey/models"
@@ -42,9 +44,7 @@ func (o *ConsoleColoredOutput) Process(t models.TestInterface, result *models.Re
 
 func renderResult(result *models.Result) (string, error) {
 	text := `
-       Name: {{ green .Test.GetName }}
-       File: {{ green .Test.GetFileName }}
-
+{{- define "exchange" -}}
 Request:
      Method: {{ cyan .Test.GetMethod }}
        Path: {{ cyan .Test.Path }}
@@ -66,8 +66,29 @@ Request:
 
 Response:
      Status: {{ cyan .ResponseStatus }}
+{{- if .Attempts }}
+   Attempts: {{ len .Attempts }}
+{{- end }}
+{{- if .Timings.Total }}
+       Time: {{ cyan .Timings.String }}
+{{- end }}
        Body:
 {{ if .ResponseBody }}{{ yellow .ResponseBody }}{{ else }}{{ yellow "<no body>" }}{{ end }}
+{{- if .Stderr }}
+     Stderr:
+{{ yellow .Stderr }}
+{{- end }}
+{{- end }}
+       Name: {{ green .Test.GetName }}
+       File: {{ green .Test.GetFileName }}
+{{ if .Steps }}
+{{- range $i, $step := .Steps }}
+Step #{{ inc $i }}: {{ green $step.Test.GetName }}
+{{ template "exchange" $step }}
+{{ end }}
+{{- else }}
+{{ template "exchange" . }}
+{{- end }}
 
 {{ range $i, $dbr := .DatabaseResult }}
 {{ if $dbr.Query }}
@@ -111,6 +132,9 @@ func templateFuncMap() template.FuncMap {
 }
 
 func (o *ConsoleColoredOutput) ShowSummary(summary *models.Summary) {
+	for _, err := range summary.Errors {
+		o.coloredPrintf("\n%s %s\n", color.RedString("Error:"), err)
+	}
 	o.coloredPrintf(
 		"\nsuccess %d, failed %d, skipped %d, broken %d, total %d\n",
 		summary.Total-summary.Broken-summary.Failed-summary.Skipped,
@@ -120,3 +144,32 @@ func (o *ConsoleColoredOutput) ShowSummary(summary *models.Summary) {
 		summary.Total,
 	)
 }
+
+func (o *ConsoleColoredOutput) ShowLoadSummary(summary *models.LoadSummary) {
+	o.coloredPrintf(
+		"\nrequests %d, errors %d (%.2f%%), dropped %d, duration %s, rps %.2f\n",
+		summary.Requests,
+		summary.Errors,
+		summary.ErrorRate(),
+		summary.Dropped,
+		summary.Duration.Round(time.Millisecond),
+		summary.RPS(),
+	)
+	o.coloredPrintf(
+		"latency p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+		summary.Percentile(50).Round(time.Microsecond),
+		summary.Percentile(90).Round(time.Microsecond),
+		summary.Percentile(95).Round(time.Microsecond),
+		summary.Percentile(99).Round(time.Microsecond),
+		summary.Percentile(100).Round(time.Microsecond),
+	)
+
+	codes := make([]int, 0, len(summary.StatusCodes))
+	for code := range summary.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		o.coloredPrintf("status %d: %d\n", code, summary.StatusCodes[code])
+	}
+}
@@ -0,0 +1,209 @@
+package json_report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+type Format int
+
+const (
+	// NDJSON writes a record per line as soon as the test is processed, the summary record is the last line
+	NDJSON Format = iota
+	// JSON writes a single document with all the records on Finalize
+	JSON
+)
+
+type testRecord struct {
+	Type       string           `json:"type"`
+	Name       string           `json:"name"`
+	File       string           `json:"file"`
+	Status     string           `json:"status"`
+	DurationMs int64            `json:"durationMs"`
+	Request    *requestRecord   `json:"request,omitempty"`
+	Response   *responseRecord  `json:"response,omitempty"`
+	Attempts   int              `json:"attempts,omitempty"`
+	Steps      []testRecord     `json:"steps,omitempty"`
+	DbResults  []dbResultRecord `json:"dbResults,omitempty"`
+	MockCalls  []mockCallRecord `json:"mockCalls,omitempty"`
+	Errors     []string         `json:"errors,omitempty"`
+}
+
+type requestRecord struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+type responseRecord struct {
+	StatusCode  int                 `json:"statusCode"`
+	Status      string              `json:"status"`
+	ContentType string              `json:"contentType,omitempty"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Body        string              `json:"body,omitempty"`
+	Stderr      string              `json:"stderr,omitempty"`
+	Timings     timingsRecord       `json:"timings"`
+}
+
+type timingsRecord struct {
+	DNSLookupMs       float64 `json:"dnsLookupMs"`
+	ConnectMs         float64 `json:"connectMs"`
+	TLSHandshakeMs    float64 `json:"tlsHandshakeMs"`
+	TimeToFirstByteMs float64 `json:"timeToFirstByteMs"`
+	TotalMs           float64 `json:"totalMs"`
+}
+
+type dbResultRecord struct {
+	Query    string   `json:"query"`
+	Response []string `json:"response"`
+}
+
+type mockCallRecord struct {
+	Service      string      `json:"service"`
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	Query        string      `json:"query,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	RequestBody  string      `json:"requestBody,omitempty"`
+	StatusCode   int         `json:"statusCode"`
+	ResponseBody string      `json:"responseBody,omitempty"`
+	Time         time.Time   `json:"time"`
+}
+
+type summaryRecord struct {
+	Type    string `json:"type"`
+	Success bool   `json:"success"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+	Broken  int    `json:"broken"`
+	Total   int    `json:"total"`
+	// Errors don't belong to any test
+	Errors []string `json:"errors,omitempty"`
+}
+
+type document struct {
+	Tests   []testRecord  `json:"tests"`
+	Summary summaryRecord `json:"summary"`
+}
+
+// JSONReportOutput writes results of the tests in machine-readable form
+type JSONReportOutput struct {
+	format  Format
+	encoder *json.Encoder
+	records []testRecord
+}
+
+func NewOutput(w io.Writer, format Format) *JSONReportOutput {
+	return &JSONReportOutput{
+		format:  format,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (o *JSONReportOutput) Process(t models.TestInterface, result *models.Result) error {
+	record := newTestRecord(t, result)
+	if o.format == JSON {
+		o.records = append(o.records, record)
+		return nil
+	}
+	return o.encoder.Encode(record)
+}
+
+// Finalize writes the summary record, and the whole document for JSON format
+func (o *JSONReportOutput) Finalize(summary *models.Summary) error {
+	s := summaryRecord{
+		Type:    "summary",
+		Success: summary.Success,
+		Failed:  summary.Failed,
+		Skipped: summary.Skipped,
+		Broken:  summary.Broken,
+		Total:   summary.Total,
+	}
+	for _, err := range summary.Errors {
+		s.Errors = append(s.Errors, err.Error())
+	}
+	if o.format == JSON {
+		records := o.records
+		if records == nil {
+			records = []testRecord{}
+		}
+		o.encoder.SetIndent("", "  ")
+		return o.encoder.Encode(document{Tests: records, Summary: s})
+	}
+	return o.encoder.Encode(s)
+}
+
+func newTestRecord(t models.TestInterface, result *models.Result) testRecord {
+	record := testRecord{
+		Type:       "test",
+		Name:       t.GetName(),
+		File:       t.GetFileName(),
+		Status:     testStatus(t, result),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+
+	for _, err := range result.Errors {
+		record.Errors = append(record.Errors, err.Error())
+	}
+	for _, c := range result.MockCalls {
+		record.MockCalls = append(record.MockCalls, mockCallRecord(c))
+	}
+
+	// tests which were not run have nothing but the status
+	if result.Test == nil || t.GetStatus() == "skipped" || t.GetStatus() == "broken" {
+		return record
+	}
+
+	for _, step := range result.Steps {
+		record.Steps = append(record.Steps, newTestRecord(step.Test, step))
+	}
+	if len(result.Steps) == 0 {
+		record.Request = &requestRecord{
+			Method: t.GetMethod(),
+			Path:   t.Path(),
+			Query:  result.Query,
+			Body:   result.RequestBody,
+		}
+		record.Response = &responseRecord{
+			StatusCode:  result.ResponseStatusCode,
+			Status:      result.ResponseStatus,
+			ContentType: result.ResponseContentType,
+			Headers:     result.ResponseHeaders,
+			Body:        result.ResponseBody,
+			Stderr:      result.Stderr,
+			Timings: timingsRecord{
+				DNSLookupMs:       milliseconds(result.Timings.DNSLookup),
+				ConnectMs:         milliseconds(result.Timings.Connect),
+				TLSHandshakeMs:    milliseconds(result.Timings.TLSHandshake),
+				TimeToFirstByteMs: milliseconds(result.Timings.TimeToFirstByte),
+				TotalMs:           milliseconds(result.Timings.Total),
+			},
+		}
+		record.Attempts = len(result.Attempts)
+	}
+	for _, dbr := range result.DatabaseResult {
+		record.DbResults = append(record.DbResults, dbResultRecord(dbr))
+	}
+
+	return record
+}
+
+func testStatus(t models.TestInterface, result *models.Result) string {
+	switch t.GetStatus() {
+	case "skipped", "broken":
+		return t.GetStatus()
+	}
+	if result.Passed() {
+		return "passed"
+	}
+	return "failed"
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
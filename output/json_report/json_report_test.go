@@ -0,0 +1,86 @@
+package json_report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+)
+
+func newTest(name, status string) *yaml_file.Test {
+	test := &yaml_file.Test{Filename: "cases/orders.yaml"}
+	test.Name = name
+	test.Method = "POST"
+	test.RequestURL = "/orders"
+	test.Status = status
+	return test
+}
+
+func TestNDJSONShouldWriteRecordPerTestAndSummary(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(&buf, NDJSON)
+
+	failed := newTest("create order", "")
+	skipped := newTest("delete order", "skipped")
+
+	require.NoError(t, o.Process(failed, &models.Result{
+		Test:               failed,
+		RequestBody:        `{"sku": "a1"}`,
+		ResponseStatusCode: 500,
+		ResponseStatus:     "500 Internal Server Error",
+		Errors:             []error{errors.New("server responded with status 500")},
+		Duration:           1500 * time.Millisecond,
+		MockCalls: []models.MockCall{
+			{Service: "stock", Method: "GET", Path: "/reserve", StatusCode: 200},
+		},
+	}))
+	require.NoError(t, o.Process(skipped, &models.Result{Test: skipped}))
+	require.NoError(t, o.Finalize(&models.Summary{Failed: 1, Skipped: 1, Total: 2}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "test", record["type"])
+	assert.Equal(t, "create order", record["name"])
+	assert.Equal(t, "cases/orders.yaml", record["file"])
+	assert.Equal(t, "failed", record["status"])
+	assert.Equal(t, float64(1500), record["durationMs"])
+	assert.Equal(t, map[string]interface{}{"method": "POST", "path": "/orders", "body": `{"sku": "a1"}`}, record["request"])
+	assert.Equal(t, []interface{}{"server responded with status 500"}, record["errors"])
+	assert.Len(t, record["mockCalls"], 1)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, "skipped", record["status"])
+
+	assert.JSONEq(t, `{"type": "summary", "success": false, "failed": 1, "skipped": 1, "broken": 0, "total": 2}`, lines[2])
+}
+
+func TestJSONShouldWriteSingleDocument(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(&buf, JSON)
+
+	passed := newTest("create order", "")
+	require.NoError(t, o.Process(passed, &models.Result{Test: passed, ResponseStatusCode: 200}))
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, o.Finalize(&models.Summary{Success: true, Total: 1}))
+
+	var doc struct {
+		Tests   []map[string]interface{}
+		Summary map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Tests, 1)
+	assert.Equal(t, "passed", doc.Tests[0]["status"])
+	assert.Equal(t, true, doc.Summary["success"])
+}
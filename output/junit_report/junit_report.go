@@ -0,0 +1,190 @@
+package junit_report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+type testSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []testSuite `xml:"testsuite"`
+}
+
+type testSuite struct {
+	Name     string     `xml:"name,attr"`
+	Tests    int        `xml:"tests,attr"`
+	Failures int        `xml:"failures,attr"`
+	Errors   int        `xml:"errors,attr"`
+	Skipped  int        `xml:"skipped,attr"`
+	Time     string     `xml:"time,attr"`
+	Cases    []testCase `xml:"testcase"`
+	duration time.Duration
+}
+
+type testCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      string   `xml:"time,attr"`
+	Failure   *message `xml:"failure,omitempty"`
+	Error     *message `xml:"error,omitempty"`
+	Skipped   *message `xml:"skipped,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+}
+
+type message struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitReportOutput collects results of the tests and writes them as JUnit XML report on Finalize.
+// Every test file is reported as a test suite, every test (or case of the test) as a test case.
+type JUnitReportOutput struct {
+	name       string
+	reportFile string
+	suites     []*testSuite
+	suiteIdx   map[string]int
+}
+
+func NewOutput(name, reportFile string) *JUnitReportOutput {
+	return &JUnitReportOutput{
+		name:       name,
+		reportFile: reportFile,
+		suiteIdx:   make(map[string]int),
+	}
+}
+
+func (o *JUnitReportOutput) Process(t models.TestInterface, result *models.Result) error {
+	suite := o.suite(t.GetFileName())
+
+	tc := testCase{
+		Name:      t.GetName(),
+		ClassName: suite.Name,
+		Time:      seconds(result.Duration),
+	}
+
+	switch t.GetStatus() {
+	case "skipped":
+		tc.Skipped = &message{Message: "test is skipped"}
+		suite.Skipped++
+	case "broken":
+		if len(result.Errors) != 0 {
+			tc.Error = &message{Message: "test is broken", Text: renderErrors(result.Errors)}
+			tc.SystemOut = renderExchange(result)
+			suite.Errors++
+		} else {
+			tc.Skipped = &message{Message: "test is marked as broken"}
+			suite.Skipped++
+		}
+	default:
+		if len(result.Errors) != 0 {
+			tc.Failure = &message{
+				Message: result.Errors[0].Error(),
+				Text:    renderErrors(result.Errors),
+			}
+			suite.Failures++
+		}
+		tc.SystemOut = renderExchange(result)
+	}
+
+	suite.Tests++
+	suite.duration += result.Duration
+	suite.Cases = append(suite.Cases, tc)
+
+	return nil
+}
+
+func (o *JUnitReportOutput) suite(fileName string) *testSuite {
+	if idx, ok := o.suiteIdx[fileName]; ok {
+		return o.suites[idx]
+	}
+	o.suiteIdx[fileName] = len(o.suites)
+	s := &testSuite{Name: fileName}
+	o.suites = append(o.suites, s)
+	return s
+}
+
+// Finalize writes the report to the file
+func (o *JUnitReportOutput) Finalize() error {
+	report := testSuites{Name: o.name}
+	var duration time.Duration
+	for _, s := range o.suites {
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.Errors += s.Errors
+		report.Skipped += s.Skipped
+		s.Time = seconds(s.duration)
+		duration += s.duration
+		report.Suites = append(report.Suites, *s)
+	}
+	report.Time = seconds(duration)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.reportFile), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(o.reportFile, append([]byte(xml.Header), data...), 0644)
+}
+
+// seconds formats the duration as JUnit time attribute
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func renderErrors(errs []error) string {
+	var b strings.Builder
+	for i, err := range errs {
+		fmt.Fprintf(&b, "%d) %s\n", i+1, err)
+	}
+	return b.String()
+}
+
+func renderExchange(result *models.Result) string {
+	if len(result.Steps) == 0 {
+		return renderRequest(result)
+	}
+
+	var b strings.Builder
+	for i, step := range result.Steps {
+		fmt.Fprintf(&b, "Step #%d: %s\n%s\n", i+1, step.Test.GetName(), renderRequest(step))
+	}
+	return b.String()
+}
+
+func renderRequest(result *models.Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Request:\n")
+	if result.Test != nil {
+		fmt.Fprintf(&b, "  Method: %s\n  Path: %s\n", result.Test.GetMethod(), result.Test.Path())
+	}
+	fmt.Fprintf(&b, "  Query: %s\n  Body:\n%s\n", result.Query, result.RequestBody)
+	fmt.Fprintf(&b, "Response:\n  Status: %s\n", result.ResponseStatus)
+	if len(result.Attempts) != 0 {
+		fmt.Fprintf(&b, "  Attempts: %d\n", len(result.Attempts))
+	}
+	fmt.Fprintf(&b, "  Body:\n%s\n", result.ResponseBody)
+	if result.Stderr != "" {
+		fmt.Fprintf(&b, "  Stderr:\n%s\n", result.Stderr)
+	}
+	for i, dbr := range result.DatabaseResult {
+		fmt.Fprintf(&b, "Db Request #%d:\n%s\nDb Response #%d:\n%s\n", i+1, dbr.Query, i+1, strings.Join(dbr.Response, "\n"))
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package junit_report
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+)
+
+func newTest(file, name, status string) *yaml_file.Test {
+	test := &yaml_file.Test{Filename: file}
+	test.Name = name
+	test.Status = status
+	return test
+}
+
+func TestFinalizeShouldWriteSuitesPerFile(t *testing.T) {
+	reportFile := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	o := NewOutput("Gonkey", reportFile)
+
+	passed := newTest("cases/orders.yaml", "create order", "")
+	failed := newTest("cases/orders.yaml", "get order #1", "")
+	skipped := newTest("cases/users.yaml", "get user", "skipped")
+	broken := newTest("cases/users.yaml", "delete user", "broken")
+
+	require.NoError(t, o.Process(passed, &models.Result{Test: passed, ResponseStatus: "200 OK", ResponseBody: `{"id": 1}`, Duration: 1500 * time.Millisecond}))
+	require.NoError(t, o.Process(failed, &models.Result{Test: failed, Errors: []error{errors.New("status code 404 is not expected")}, Duration: 250 * time.Millisecond}))
+	require.NoError(t, o.Process(skipped, &models.Result{Test: skipped}))
+	require.NoError(t, o.Process(broken, &models.Result{Test: broken}))
+
+	require.NoError(t, o.Finalize())
+
+	data, err := ioutil.ReadFile(reportFile)
+	require.NoError(t, err)
+
+	report := string(data)
+	assert.Contains(t, report, `<testsuites name="Gonkey" tests="4" failures="1" errors="0" skipped="2" time="1.750">`)
+	assert.Contains(t, report, `<testsuite name="cases/orders.yaml" tests="2" failures="1" errors="0" skipped="0" time="1.750">`)
+	assert.Contains(t, report, `<testsuite name="cases/users.yaml" tests="2" failures="0" errors="0" skipped="2" time="0.000">`)
+	assert.Contains(t, report, `<testcase name="create order" classname="cases/orders.yaml" time="1.500">`)
+	assert.Contains(t, report, `<failure message="status code 404 is not expected">1) status code 404 is not expected`)
+	assert.Contains(t, report, `<skipped message="test is marked as broken"></skipped>`)
+	assert.Contains(t, report, `{&#34;id&#34;: 1}`)
+}
+
+func TestProcessShouldReportRequestOfBrokenTest(t *testing.T) {
+	reportFile := filepath.Join(t.TempDir(), "junit.xml")
+	o := NewOutput("Gonkey", reportFile)
+
+	broken := newTest("cases/orders.yaml", "create order", "broken")
+	require.NoError(t, o.Process(broken, &models.Result{
+		Test:         broken,
+		ResponseBody: `{"error": "fixtures"}`,
+		Errors:       []error{errors.New("unable to load fixtures")},
+	}))
+	require.NoError(t, o.Finalize())
+
+	data, err := ioutil.ReadFile(reportFile)
+	require.NoError(t, err)
+
+	report := string(data)
+	assert.Contains(t, report, `<error message="test is broken">1) unable to load fixtures`)
+	assert.Contains(t, report, `<system-out>Request:`)
+	assert.Contains(t, report, `{&#34;error&#34;: &#34;fixtures&#34;}`)
+}
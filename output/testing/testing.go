@@ -31,9 +31,7 @@ func (o *TestingOutput) Process(t models.TestInterface, result *models.Result) e
 
 func renderResult(result *models.Result) (string, error) {
 	text := `
-       Name: {{ .Test.GetName }}
-       File: {{ .Test.GetFileName }}
-
+{{- define "exchange" -}}
 Request:
      Method: {{ .Test.GetMethod }}
        Path: {{ .Test.Path }}
@@ -55,8 +53,29 @@ Request:
 
 Response:
      Status: {{ .ResponseStatus }}
+{{- if .Attempts }}
+   Attempts: {{ len .Attempts }}
+{{- end }}
+{{- if .Timings.Total }}
+       Time: {{ .Timings.String }}
+{{- end }}
        Body:
 {{ if .ResponseBody }}{{ .ResponseBody }}{{ else }}{{ "<no body>" }}{{ end }}
+{{- if .Stderr }}
+     Stderr:
+{{ .Stderr }}
+{{- end }}
+{{- end }}
+       Name: {{ .Test.GetName }}
+       File: {{ .Test.GetFileName }}
+{{ if .Steps }}
+{{- range $i, $step := .Steps }}
+Step #{{ inc $i }}: {{ $step.Test.GetName }}
+{{ template "exchange" $step }}
+{{ end }}
+{{- else }}
+{{ template "exchange" . }}
+{{- end }}
 
 {{ range $i, $dbr := .DatabaseResult }}
 {{ if $dbr.Query }}
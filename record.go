@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/lamoda/gonkey/recorder"
+)
+
+// record runs the proxy which records the requests to the tested service as tests:
+// gonkey record -target <...> [-listen <...>] [-out <...>] [-masks <...>]
+func record(args []string) {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	target := flags.String("target", "", "URL of the tested service, e.g. http://localhost:8080")
+	listen := flags.String("listen", ":8081", "Address the proxy listens on")
+	out := flags.String("out", "recorded.yaml", "Path to the file the tests are written to")
+	masksFile := flags.String("masks", "", "Path to YAML file with the masks of volatile values of the responses")
+	_ = flags.Parse(args)
+
+	if *target == "" {
+		log.Fatal("target of the proxy is not provided")
+	}
+
+	var masks []recorder.Mask
+	if *masksFile != "" {
+		var err error
+		if masks, err = recorder.LoadMasks(*masksFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	rec, err := recorder.New(*target, masks)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorded := rec.Len()
+		rec.ServeHTTP(w, r)
+		if rec.Len() == recorded {
+			return
+		}
+		// the file is rewritten after every request, so nothing is lost when the proxy is interrupted
+		if err := rec.Save(*out); err != nil {
+			log.Printf("unable to write %s: %s", *out, err)
+		}
+	})
+
+	log.Printf("Recording requests to %s on %s into %s", *target, *listen, *out)
+	log.Fatal(http.ListenAndServe(*listen, handler))
+}
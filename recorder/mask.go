@@ -0,0 +1,121 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/textproto"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Mask replaces volatile values of the recorded responses with `$matchRegexp` patterns.
+// One of Field, Header or Value must be set.
+type Mask struct {
+	// Field is the name of JSON field of the response body masked at any depth
+	Field string `json:"field" yaml:"field"`
+	// Header is the name of the response header
+	Header string `json:"header" yaml:"header"`
+	// Value is a regular expression, string values of the response body matching it are masked in any field
+	Value string `json:"value" yaml:"value"`
+	// Pattern is the regular expression written to $matchRegexp,
+	// Value is used by default for Value masks and `.*` for the others
+	Pattern string `json:"pattern" yaml:"pattern"`
+}
+
+type compiledMask struct {
+	Mask
+	value *regexp.Regexp
+}
+
+// LoadMasks reads the list of the masks from YAML file
+func LoadMasks(path string) ([]Mask, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var masks []Mask
+	if err := yaml.Unmarshal(data, &masks); err != nil {
+		return nil, fmt.Errorf("unable to parse masks file %s: %w", path, err)
+	}
+	return masks, nil
+}
+
+func compileMasks(masks []Mask) ([]compiledMask, error) {
+	res := make([]compiledMask, 0, len(masks))
+	for i, m := range masks {
+		c := compiledMask{Mask: m}
+		switch {
+		case m.Field != "" || m.Header != "":
+			if c.Pattern == "" {
+				c.Pattern = ".*"
+			}
+		case m.Value != "":
+			rx, err := regexp.Compile(m.Value)
+			if err != nil {
+				return nil, fmt.Errorf("mask #%d: %w", i+1, err)
+			}
+			c.value = rx
+			if c.Pattern == "" {
+				c.Pattern = m.Value
+			}
+		default:
+			return nil, fmt.Errorf("mask #%d: %w", i+1, errors.New("one of field, header or value is expected"))
+		}
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return nil, fmt.Errorf("mask #%d: %w", i+1, err)
+		}
+		c.Header = textproto.CanonicalMIMEHeaderKey(c.Header)
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func matchRegexp(pattern string) string {
+	return "$matchRegexp(" + pattern + ")"
+}
+
+// maskHeader returns the masked value of the response header
+func maskHeader(masks []compiledMask, name, value string) string {
+	for _, m := range masks {
+		if m.Header != "" && m.Header == name {
+			return matchRegexp(m.Pattern)
+		}
+	}
+	return value
+}
+
+// maskBody replaces the masked values of the decoded JSON body
+func maskBody(masks []compiledMask, body interface{}) interface{} {
+	switch v := body.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if pattern, ok := fieldPattern(masks, key); ok {
+				v[key] = matchRegexp(pattern)
+			} else {
+				v[key] = maskBody(masks, value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = maskBody(masks, value)
+		}
+	case string:
+		for _, m := range masks {
+			if m.value != nil && m.value.MatchString(v) {
+				return matchRegexp(m.Pattern)
+			}
+		}
+	}
+	return body
+}
+
+func fieldPattern(masks []compiledMask, key string) (string, bool) {
+	for _, m := range masks {
+		if m.Field != "" && m.Field == key {
+			return m.Pattern, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,219 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lamoda/gonkey/compare"
+)
+
+// skippedRequestHeaders are set by the client or the transport and are not recorded to the tests
+var skippedRequestHeaders = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"User-Agent":        true,
+}
+
+// skippedResponseHeaders are different in every response or set by the transport
+var skippedResponseHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+}
+
+// test is the recorded request in the format of the test file
+type test struct {
+	Name             string                    `yaml:"name"`
+	Method           string                    `yaml:"method"`
+	Path             string                    `yaml:"path"`
+	Query            string                    `yaml:"query,omitempty"`
+	Headers          map[string]string         `yaml:"headers,omitempty"`
+	Request          string                    `yaml:"request,omitempty"`
+	Response         map[int]string            `yaml:"response"`
+	ResponseHeaders  map[int]map[string]string `yaml:"responseHeaders,omitempty"`
+	ComparisonParams compare.CompareParams     `yaml:"comparisonParams"`
+}
+
+// Recorder is a proxy to the tested service which records the requests and responses as tests
+type Recorder struct {
+	proxy *httputil.ReverseProxy
+	masks []compiledMask
+
+	mu    sync.Mutex
+	tests []test
+}
+
+// New creates the recorder of the requests to the target URL
+func New(target string, masks []Mask) (*Recorder, error) {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if targetURL.Scheme == "" || targetURL.Host == "" {
+		return nil, fmt.Errorf("target %s should be an absolute URL, e.g. http://localhost:8080", target)
+	}
+	compiled, err := compileMasks(masks)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = targetURL.Host
+		// the response body is recorded as is, so it must not be compressed
+		req.Header.Del("Accept-Encoding")
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		// the service didn't respond, there is nothing to record
+		if rw, ok := w.(*responseRecorder); ok {
+			rw.failed = true
+		}
+		log.Printf("proxy error: %s", err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	return &Recorder{proxy: proxy, masks: compiled}, nil
+}
+
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = ioutil.ReadAll(req.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+	}
+	headers := requestHeaders(req.Header)
+
+	rw := newResponseRecorder(w)
+	r.proxy.ServeHTTP(rw, req)
+	if rw.failed {
+		return
+	}
+
+	t := test{
+		Name:             req.Method + " " + req.URL.Path,
+		Method:           req.Method,
+		Path:             req.URL.Path,
+		Headers:          headers,
+		Request:          string(requestBody),
+		Response:         map[int]string{rw.statusCode: r.responseBody(rw.body.Bytes())},
+		ComparisonParams: compare.CompareParams{},
+	}
+	if req.URL.RawQuery != "" {
+		t.Query = "?" + req.URL.RawQuery
+	}
+	if h := r.responseHeaders(rw.Header()); len(h) != 0 {
+		t.ResponseHeaders = map[int]map[string]string{rw.statusCode: h}
+	}
+
+	r.mu.Lock()
+	r.tests = append(r.tests, t)
+	r.mu.Unlock()
+}
+
+// Len returns the number of the recorded tests
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tests)
+}
+
+// Marshal returns the recorded tests as the test file
+func (r *Recorder) Marshal() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return yaml.Marshal(r.tests)
+}
+
+// Save writes the recorded tests to the file
+func (r *Recorder) Save(path string) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func requestHeaders(header http.Header) map[string]string {
+	res := make(map[string]string)
+	for name, values := range header {
+		if !skippedRequestHeaders[name] && len(values) != 0 {
+			res[name] = values[0]
+		}
+	}
+	return res
+}
+
+func (r *Recorder) responseHeaders(header http.Header) map[string]string {
+	res := make(map[string]string)
+	for name, values := range header {
+		if !skippedResponseHeaders[name] && len(values) != 0 {
+			res[name] = maskHeader(r.masks, name, values[0])
+		}
+	}
+	return res
+}
+
+// responseBody returns the body with masked values, JSON is formatted to be readable in the test
+func (r *Recorder) responseBody(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil || decoder.More() {
+		return string(body)
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(maskBody(r.masks, decoded)); err != nil {
+		return string(body)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// responseRecorder passes the response to the client and remembers its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	failed     bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,94 @@
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/checker/response_header"
+	"github.com/lamoda/gonkey/runner"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestRecorder(t *testing.T) {
+	var lastID int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := atomic.AddInt64(&lastID, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", fmt.Sprintf("req-%d", id))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"id": %d, "name": %s, "createdAt": "%s", "tags": ["new"], "big": 12345678901234567890}`,
+			id, body, time.Now().Format(time.RFC3339Nano))
+	}))
+	defer srv.Close()
+
+	rec, err := New(srv.URL, []Mask{
+		{Field: "id", Pattern: `^\d+$`},
+		{Value: `^\d{4}-\d{2}-\d{2}T`},
+		{Header: "x-request-id"},
+	})
+	require.NoError(t, err)
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	req, err := http.NewRequest(http.MethodPost, proxy.URL+"/items?kind=book", strings.NewReader(`"Dune"`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Contains(t, string(body), `"name": "Dune"`)
+
+	data, err := rec.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id": "$matchRegexp(^\\d+$)"`)
+	assert.Contains(t, string(data), `"createdAt": "$matchRegexp(^\\d{4}-\\d{2}-\\d{2}T)"`)
+	assert.Contains(t, string(data), `"big": 12345678901234567890`)
+	assert.NotContains(t, string(data), "Date:")
+	assert.NotContains(t, string(data), "User-Agent")
+
+	dir, err := ioutil.TempDir("", "gonkey-recorder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "recorded.yaml")
+	require.NoError(t, rec.Save(file))
+
+	// the recorded test is loaded and passes against the service
+	loaded, err := yaml_file.NewLoader(file).Load()
+	require.NoError(t, err)
+	var tests []*yaml_file.Test
+	for v := range loaded {
+		tests = append(tests, v.(*yaml_file.Test))
+	}
+	require.Len(t, tests, 1)
+	assert.Equal(t, "POST /items", tests[0].GetName())
+	assert.Equal(t, "/items", tests[0].Path())
+	assert.Equal(t, `"Dune"`, tests[0].Request)
+	assert.Equal(t, map[string]string{"Content-Type": "application/json"}, tests[0].Headers())
+	assert.Equal(t, "$matchRegexp(.*)", tests[0].ResponseHeaders[http.StatusCreated]["X-Request-Id"])
+
+	r := runner.New(
+		&runner.Config{Host: srv.URL, Variables: variables.New()},
+		yaml_file.NewLoader(file),
+	)
+	r.AddCheckers(response_body.NewChecker(), response_header.NewChecker())
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, 1, summary.Total)
+}
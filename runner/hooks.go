@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/lamoda/gonkey/cmd_runner"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/variables"
+)
+
+// fileHooksState tracks execution of the hooks of a test file
+type fileHooksState struct {
+	once sync.Once
+	// err is the error of beforeAll hooks, the tests of the file are broken if it is set
+	err error
+	// vars are set by beforeAll hooks and are loaded before every test of the file
+	vars      *variables.Variables
+	executed  bool
+	remaining int
+}
+
+// hooksTracker runs beforeAll hooks of a file before its first executed test
+// and afterAll hooks after its last test, the tests may be executed in parallel
+type hooksTracker struct {
+	sync.Mutex
+	files map[*models.Hooks]*fileHooksState
+	// runErr is the error of beforeAll hooks of the run, all the tests are broken if it is set
+	runErr error
+}
+
+func newHooksTracker(tests []models.TestInterface) *hooksTracker {
+	t := &hooksTracker{files: make(map[*models.Hooks]*fileHooksState)}
+	for _, v := range tests {
+		if h := v.GetFileHooks(); h != nil {
+			if _, ok := t.files[h]; !ok {
+				t.files[h] = &fileHooksState{}
+			}
+			t.files[h].remaining++
+		}
+	}
+	return t
+}
+
+func (t *hooksTracker) state(h *models.Hooks) *fileHooksState {
+	t.Lock()
+	defer t.Unlock()
+	return t.files[h]
+}
+
+func hookName(h models.Hook, i int) string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// runHooks makes the hooks one by one until the first failure.
+// Variables set by the hooks are added to vars and returned separately.
+func (r *Runner) runHooks(kind string, hooks []models.Hook, client *http.Client, vars *variables.Variables) (*variables.Variables, error) {
+	setVars := variables.New()
+	for i, h := range hooks {
+		h = vars.ApplyHook(h)
+
+		hookVars, err := r.runHook(h, client)
+		if err != nil {
+			return setVars, fmt.Errorf("%s hook %s failed: %w", kind, hookName(h, i), err)
+		}
+		if hookVars != nil {
+			vars.Merge(hookVars)
+			setVars.Merge(hookVars)
+		}
+	}
+	return setVars, nil
+}
+
+func (r *Runner) runHook(h models.Hook, client *http.Client) (*variables.Variables, error) {
+	switch {
+	case h.Request != nil:
+		return r.runRequestHook(h, client)
+	case h.DbExec != "":
+		return r.runDbHook(h)
+	case h.Fixtures != nil:
+		if r.config.FixturesLoader == nil {
+			return nil, errors.New("fixtures loader is not configured")
+		}
+		return nil, r.loadFixtures(h.Fixtures)
+	case h.Script != nil:
+		return nil, cmd_runner.CmdRun(h.Script.Path, h.Script.Timeout)
+	default:
+		return nil, errors.New("hook has no action, one of request, dbExec, fixtures or script is expected")
+	}
+}
+
+func (r *Runner) runRequestHook(h models.Hook, client *http.Client) (*variables.Variables, error) {
+	method := h.Request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), r.config.Host+h.Request.Path, bytes.NewBufferString(h.Request.Body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h.Request.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Request.Status != 0 && resp.StatusCode != h.Request.Status ||
+		h.Request.Status == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return nil, fmt.Errorf("unexpected response status %s, body:\n%s", resp.Status, body)
+	}
+
+	if h.VariablesToSet == nil {
+		return nil, nil
+	}
+	isJson := strings.Contains(resp.Header.Get("Content-Type"), "json") && len(body) != 0
+	return variables.FromResponse(h.VariablesToSet, string(body), isJson)
+}
+
+func (r *Runner) runDbHook(h models.Hook) (*variables.Variables, error) {
+	if r.config.DB == nil {
+		return nil, errors.New("database is not configured")
+	}
+
+	if h.VariablesToSet == nil {
+		_, err := r.config.DB.Exec(h.DbExec)
+		return nil, err
+	}
+
+	rows, err := r.config.DB.Query(h.DbExec)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no rows to set variables from")
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]string, len(columns))
+	for i, column := range columns {
+		switch v := values[i].(type) {
+		case nil:
+			row[column] = ""
+		case []byte:
+			row[column] = string(v)
+		default:
+			row[column] = fmt.Sprint(v)
+		}
+	}
+
+	vars := variables.New()
+	for name, column := range h.VariablesToSet {
+		value, ok := row[column]
+		if !ok {
+			return nil, fmt.Errorf("column %s is not returned by the statement", column)
+		}
+		vars.Set(name, value)
+	}
+	return vars, nil
+}
+
+// runBeforeAll makes beforeAll hooks of the run, the failure is remembered and breaks all the tests
+func (r *Runner) runBeforeAll(client *http.Client) {
+	if r.config.Hooks == nil {
+		return
+	}
+	_, err := r.runHooks("beforeAll", r.config.Hooks.BeforeAll, client, r.config.Variables)
+	r.hooks.runErr = err
+}
+
+// runAfterAll makes afterAll hooks of the run, the failure doesn't affect results of the tests
+func (r *Runner) runAfterAll(client *http.Client) {
+	if r.config.Hooks == nil {
+		return
+	}
+	if _, err := r.runHooks("afterAll", r.config.Hooks.AfterAll, client, r.config.Variables); err != nil {
+		fmt.Printf("Gonkey: %s\n", err)
+	}
+}
+
+// executeTestWithHooks executes the test making the hooks it depends on,
+// the test is broken if the hooks fail, the failed test stays failed
+func (r *Runner) executeTestWithHooks(v models.TestInterface, client *http.Client, vars *variables.Variables) (*models.Result, error) {
+	if v.GetStatus() == "skipped" || v.GetStatus() == "broken" {
+		return r.executeTest(v, client, vars)
+	}
+
+	if r.hooks.runErr != nil {
+		return brokenResult(v, r.hooks.runErr)
+	}
+
+	if h := v.GetFileHooks(); h != nil {
+		state := r.hooks.state(h)
+		state.once.Do(func() {
+			state.vars, state.err = r.runHooks("beforeAll", h.BeforeAll, client, vars.Clone())
+		})
+
+		r.hooks.Lock()
+		state.executed = true
+		r.hooks.Unlock()
+
+		if state.err != nil {
+			return brokenResult(v, state.err)
+		}
+		vars.Merge(state.vars)
+	}
+
+	result, err := r.executeTest(v, client, vars)
+	if err != nil || v.GetAfterTestHooks() == nil {
+		return result, err
+	}
+
+	if _, err := r.runHooks("afterTest", v.GetAfterTestHooks(), client, vars); err != nil {
+		result.Errors = append(result.Errors, err)
+		// the test which has already failed stays failed, so the run isn't successful
+		if len(result.Errors) > 1 {
+			return result, nil
+		}
+		v.SetStatus("broken")
+		if result.Test != nil {
+			result.Test.SetStatus("broken")
+		}
+		return result, errTestBroken
+	}
+	return result, nil
+}
+
+// testDone makes afterAll hooks of the file after its last test
+func (r *Runner) testDone(v models.TestInterface, client *http.Client, vars *variables.Variables) {
+	h := v.GetFileHooks()
+	if h == nil {
+		return
+	}
+
+	r.hooks.Lock()
+	state := r.hooks.files[h]
+	state.remaining--
+	isLast := state.remaining == 0 && state.executed
+	r.hooks.Unlock()
+
+	if !isLast {
+		return
+	}
+
+	hookVars := vars.Clone()
+	if state.vars != nil {
+		hookVars.Merge(state.vars)
+	}
+	if _, err := r.runHooks("afterAll", h.AfterAll, client, hookVars); err != nil {
+		fmt.Printf("Gonkey: %s of %s\n", err, v.GetFileName())
+	}
+}
+
+func brokenResult(v models.TestInterface, reason error) (*models.Result, error) {
+	v.SetStatus("broken")
+	return &models.Result{Test: v, Errors: []error{reason}}, errTestBroken
+}
@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/variables"
+)
+
+// LoadConfig describes the load test made of the regular tests
+type LoadConfig struct {
+	// Duration of the load test
+	Duration time.Duration
+	// RPS is the rate of requests per second, if not set the requests are sent
+	// one after another by each of Concurrency workers
+	RPS int
+	// Concurrency is the number of requests sent at the same time, 1 by default
+	Concurrency int
+}
+
+type loadStats struct {
+	sync.Mutex
+	summary models.LoadSummary
+}
+
+func (s *loadStats) add(statusCode int, latency time.Duration, failed bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.summary.Requests++
+	s.summary.Latencies = append(s.summary.Latencies, latency)
+	if statusCode != 0 {
+		s.summary.StatusCodes[statusCode]++
+	}
+	if failed {
+		s.summary.Errors++
+	}
+}
+
+func (s *loadStats) drop() {
+	s.Lock()
+	defer s.Unlock()
+	s.summary.Dropped++
+}
+
+// RunLoad replays the tests round-robin during the configured time.
+//
+// Fixtures of all the tests are loaded once before the load starts. Each worker has its own
+// copy of the variables. Requests are not repeated according to `retry` settings, a request
+// which failed or didn't pass the checks is counted as an error. Mock servers are shared,
+// so the tests which define mocks are sent one at a time.
+func (r *Runner) RunLoad(cfg LoadConfig) (*models.LoadSummary, error) {
+	if cfg.Duration <= 0 {
+		return nil, errors.New("duration of the load test is not set")
+	}
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if r.loader == nil {
+		return &models.LoadSummary{}, nil
+	}
+	loaded, err := r.loadTests()
+	if err != nil {
+		return nil, err
+	}
+	var tests []models.TestInterface
+	for _, v := range loaded {
+		if v.GetStatus() != "skipped" && v.GetStatus() != "broken" {
+			tests = append(tests, v)
+		}
+	}
+	if len(tests) == 0 {
+		return nil, errors.New("no tests to run")
+	}
+
+	if err := r.loadLoadFixtures(tests); err != nil {
+		return nil, err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &loadStats{summary: models.LoadSummary{StatusCodes: make(map[int]int)}}
+	jobs := make(chan models.TestInterface, concurrency)
+
+	var next int
+	var nextMu sync.Mutex
+	nextTest := func() models.TestInterface {
+		nextMu.Lock()
+		defer nextMu.Unlock()
+		v := tests[next%len(tests)]
+		next++
+		return v
+	}
+
+	var mocksMu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vars := r.config.Variables.Clone()
+			for v := range jobs {
+				statusCode, latency, failed := r.executeLoadRequest(v, client, vars, &mocksMu)
+				stats.add(statusCode, latency, failed)
+			}
+		}()
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(cfg.Duration)
+	defer deadline.Stop()
+
+	if cfg.RPS > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(cfg.RPS))
+	rate:
+		for {
+			select {
+			case <-deadline.C:
+				break rate
+			case <-ticker.C:
+				select {
+				case jobs <- nextTest():
+				default:
+					stats.drop()
+				}
+			}
+		}
+		ticker.Stop()
+	} else {
+	concurrent:
+		for {
+			select {
+			case <-deadline.C:
+				break concurrent
+			case jobs <- nextTest():
+			}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	stats.summary.Duration = time.Since(start)
+	return &stats.summary, nil
+}
+
+// loadLoadFixtures loads fixtures of all the tests at once
+func (r *Runner) loadLoadFixtures(tests []models.TestInterface) error {
+	var names, brokerNames []string
+	seen := make(map[string]bool)
+	for _, v := range tests {
+		for _, name := range v.Fixtures() {
+			if !seen["db:"+name] {
+				seen["db:"+name] = true
+				names = append(names, name)
+			}
+		}
+		for _, name := range v.BrokerFixtures() {
+			if !seen["broker:"+name] {
+				seen["broker:"+name] = true
+				brokerNames = append(brokerNames, name)
+			}
+		}
+	}
+
+	if r.config.FixturesLoader != nil && names != nil {
+		if err := r.config.FixturesLoader.Load(names); err != nil {
+			return fmt.Errorf("unable to load fixtures [%s], error:\n%s", strings.Join(names, ", "), err)
+		}
+	}
+	if r.config.BrokerFixturesLoader != nil && brokerNames != nil {
+		if err := r.config.BrokerFixturesLoader.Load(brokerNames); err != nil {
+			return fmt.Errorf("unable to load broker fixtures [%s], error:\n%s", strings.Join(brokerNames, ", "), err)
+		}
+	}
+	return nil
+}
+
+// executeLoadRequest sends the request of the test once and checks the response,
+// the scenarios are executed as a whole and their latency includes all the steps
+func (r *Runner) executeLoadRequest(
+	v models.TestInterface,
+	client *http.Client,
+	vars *variables.Variables,
+	mocksMu *sync.Mutex,
+) (statusCode int, latency time.Duration, failed bool) {
+	// the test is shared by the workers and replayed many times,
+	// so the variables are always applied to a copy of the loaded test
+	loaded := v
+	vars.Load(v.GetVariables())
+	v = vars.Apply(loaded)
+
+	usesMocks := r.config.Mocks != nil && v.ServiceMocks() != nil
+	if usesMocks {
+		mocksMu.Lock()
+		defer mocksMu.Unlock()
+
+		r.config.Mocks.ResetDefinitions()
+		r.config.Mocks.ResetRunningContext()
+		if r.config.MocksLoader != nil {
+			if err := r.config.MocksLoader.Load(v.ServiceMocks()); err != nil {
+				return 0, 0, true
+			}
+		}
+	}
+
+	start := time.Now()
+
+	var (
+		result *models.Result
+		err    error
+	)
+	if len(v.GetSteps()) != 0 {
+		result, err = r.executeSteps(v, client, vars)
+	} else if err = r.prepareCheckers(v); err == nil {
+		result, err = r.doRequest(v, client)
+	}
+	if err != nil {
+		return 0, time.Since(start), true
+	}
+
+	latency = result.Timings.Total
+	if len(v.GetSteps()) != 0 {
+		latency = time.Since(start)
+	}
+
+	if usesMocks {
+		result.Errors = append(result.Errors, r.config.Mocks.EndRunningContext()...)
+	}
+
+	if len(v.GetSteps()) == 0 {
+		err = r.setVariablesFromResponse(v, vars, result.ResponseContentType, result.ResponseBody, result.ResponseStatusCode)
+		if err != nil {
+			return result.ResponseStatusCode, latency, true
+		}
+
+		vars.Load(v.GetVariables())
+		v = vars.Apply(loaded)
+
+		if err := r.check(v, result); err != nil {
+			return result.ResponseStatusCode, latency, true
+		}
+	}
+
+	return result.ResponseStatusCode, latency, len(result.Errors) != 0
+}
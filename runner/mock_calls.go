@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/lamoda/gonkey/compare"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/variables"
+)
+
+// mockVariables returns a copy of the variables with the requests received by the mocks during the test:
+// mock.<service>.count, mock.<service>.<first|last|N>.<method|path|query|body|status|response>
+func (r *Runner) mockVariables(vars *variables.Variables) *variables.Variables {
+	if r.config.Mocks == nil {
+		return vars
+	}
+
+	res := vars.Clone()
+	byService := make(map[string][]models.MockCall)
+	for _, call := range r.config.Mocks.Calls() {
+		byService[call.Service] = append(byService[call.Service], call)
+	}
+	for service, calls := range byService {
+		prefix := "mock." + service + "."
+		res.Set(prefix+"count", strconv.Itoa(len(calls)))
+		for i, call := range calls {
+			setMockCallVariables(res, prefix+strconv.Itoa(i)+".", call)
+		}
+		setMockCallVariables(res, prefix+"first.", calls[0])
+		setMockCallVariables(res, prefix+"last.", calls[len(calls)-1])
+	}
+	return res
+}
+
+func setMockCallVariables(vars *variables.Variables, prefix string, call models.MockCall) {
+	vars.Set(prefix+"method", call.Method)
+	vars.Set(prefix+"path", call.Path)
+	vars.Set(prefix+"query", call.Query)
+	vars.Set(prefix+"body", call.RequestBody)
+	vars.Set(prefix+"status", strconv.Itoa(call.StatusCode))
+	vars.Set(prefix+"response", call.ResponseBody)
+}
+
+// checkMockCalls compares the requests received by the mocks with the expected ones.
+// All the requests to the service mentioned in the checks must be expected.
+func checkMockCalls(v models.TestInterface, calls []models.MockCall) []error {
+	checks := v.GetMockCallChecks()
+	if len(checks) == 0 {
+		return nil
+	}
+
+	byService := make(map[string][]int)
+	for i, call := range calls {
+		byService[call.Service] = append(byService[call.Service], i)
+	}
+
+	var errs []error
+	// indexes of the calls matching the checks, -1 if the call is missing
+	matched := make([]int, len(checks))
+	expectedCount := make(map[string]int)
+	var services []string
+	for i, check := range checks {
+		n := expectedCount[check.Service]
+		if n == 0 {
+			services = append(services, check.Service)
+		}
+		expectedCount[check.Service]++
+		matched[i] = -1
+		if n >= len(byService[check.Service]) {
+			continue
+		}
+		matched[i] = byService[check.Service][n]
+		call := calls[matched[i]]
+		for _, err := range compareMockCall(check, call) {
+			errs = append(errs, fmt.Errorf("mock %s, request #%d: %w", check.Service, n+1, err))
+		}
+	}
+
+	for _, service := range services {
+		expected := expectedCount[service]
+		if actual := len(byService[service]); actual != expected {
+			errs = append(errs, fmt.Errorf(
+				"mock %s: number of requests does not match (-expected: %s +actual: %s)",
+				service,
+				color.CyanString("%d", expected),
+				color.CyanString("%d", actual),
+			))
+		}
+	}
+
+	if v.MockCallsInOrder() {
+		last := -1
+		for i, idx := range matched {
+			if idx == -1 {
+				continue
+			}
+			if idx < last {
+				errs = append(errs, fmt.Errorf(
+					"mock %s: request %s %s was received before the previous expected request",
+					checks[i].Service, calls[idx].Method, calls[idx].Path,
+				))
+			}
+			last = idx
+		}
+	}
+
+	return errs
+}
+
+// compareMockCall compares the request with the fields given in the check
+func compareMockCall(check models.MockCallCheck, call models.MockCall) []error {
+	expected := make(map[string]interface{})
+	actual := make(map[string]interface{})
+
+	if check.Method != "" {
+		expected["method"] = strings.ToUpper(check.Method)
+		actual["method"] = call.Method
+	}
+	if check.Path != "" {
+		expected["path"] = check.Path
+		actual["path"] = call.Path
+	}
+	if check.Query != "" {
+		expectedQuery, err := url.ParseQuery(strings.TrimPrefix(check.Query, "?"))
+		if err != nil {
+			return []error{fmt.Errorf("invalid expected query %s: %w", check.Query, err)}
+		}
+		actualQuery, _ := url.ParseQuery(call.Query)
+		expected["query"] = queryValues(expectedQuery)
+		actual["query"] = queryValues(actualQuery)
+	}
+	if len(check.Headers) != 0 {
+		expectedHeaders := make(map[string]interface{}, len(check.Headers))
+		actualHeaders := make(map[string]interface{}, len(check.Headers))
+		for k, v := range check.Headers {
+			expectedHeaders[k] = v
+			actualHeaders[k] = call.Headers.Get(k)
+		}
+		expected["headers"] = expectedHeaders
+		actual["headers"] = actualHeaders
+	}
+	if check.Body != "" {
+		expected["body"] = decodeBody(check.Body)
+		actual["body"] = decodeBody(call.RequestBody)
+	}
+
+	return compare.Compare(expected, actual, check.ComparisonParams)
+}
+
+func queryValues(query url.Values) map[string]interface{} {
+	res := make(map[string]interface{}, len(query))
+	for k, values := range query {
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		res[k] = list
+	}
+	return res
+}
+
+// decodeBody decodes the body from JSON, the body which is not valid JSON is compared as text
+func decodeBody(body string) interface{} {
+	var res interface{}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return body
+	}
+	return res
+}
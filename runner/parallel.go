@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/variables"
+)
+
+// testGroup is a set of tests which are executed one after another
+// in the order of definition, sharing their own copy of variables
+type testGroup struct {
+	tests    []models.TestInterface
+	isSerial bool
+	vars     *variables.Variables
+}
+
+// groupTests splits tests into isolation groups.
+//
+// Tests with the same `isolationGroup` form a group, they never overlap and may share
+// tables and topics. Tests without explicit `isolationGroup` are grouped by the file they are
+// defined in. The whole group is executed sequentially together with other tests after the
+// parallel phase if any of its tests:
+// - is marked with `parallel: false`;
+// - has no explicit isolation group and loads fixtures or publishes or checks messages
+// of the message broker, because tables and topics may be shared with other files.
+//
+// Tests using mocks don't make their groups serial, they take the lock of the mocks instead.
+func groupTests(tests []models.TestInterface) (parallel []*testGroup, serial []models.TestInterface) {
+	var groups []*testGroup
+	groupsByName := make(map[string]*testGroup)
+
+	for _, v := range tests {
+		name := "group:" + v.IsolationGroup()
+		isolated := v.IsolationGroup() != ""
+		if !isolated {
+			name = "file:" + v.GetFileName()
+		}
+
+		g, ok := groupsByName[name]
+		if !ok {
+			g = &testGroup{}
+			groupsByName[name] = g
+			groups = append(groups, g)
+		}
+		g.tests = append(g.tests, v)
+
+		if !v.Parallel() || !isolated && (usesBroker(v) || v.Fixtures() != nil) {
+			g.isSerial = true
+		}
+	}
+
+	// serial tests keep the original order
+	serialGroups := make(map[models.TestInterface]bool)
+	for _, g := range groups {
+		if !g.isSerial {
+			parallel = append(parallel, g)
+			continue
+		}
+		for _, v := range g.tests {
+			serialGroups[v] = true
+		}
+	}
+	for _, v := range tests {
+		if serialGroups[v] {
+			serial = append(serial, v)
+		}
+	}
+
+	return parallel, serial
+}
+
+// runParallel executes isolation groups on a pool of r.config.Parallel workers,
+// then executes the rest of the tests sequentially. The results are passed to the outputs
+// in the order of the tests.
+func (r *Runner) runParallel(tests []models.TestInterface, client *http.Client, stats *runStats) error {
+	groups, serial := groupTests(tests)
+	stats.keepOrder(tests)
+
+	queue := make(chan *testGroup)
+	errs := make(chan error, r.config.Parallel)
+
+	r.parallelPhase = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.config.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range queue {
+				// every group has its own copy of variables, so groups don't affect each other
+				if err := r.runSequential(g.tests, client, g.vars, stats); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for _, g := range groups {
+		g.vars = r.config.Variables.Clone()
+		select {
+		case queue <- g:
+		case err = <-errs:
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	r.parallelPhase = false
+
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// the tests executed after the parallel phase see the variables of the groups
+	for _, g := range groups {
+		r.config.Variables.Merge(g.vars)
+	}
+
+	return r.runSequential(serial, client, r.config.Variables, stats)
+}
+
+func usesMocks(v models.TestInterface) bool {
+	if v.ServiceMocks() != nil || v.GetMockCallChecks() != nil {
+		return true
+	}
+	for _, step := range v.GetSteps() {
+		if step.ServiceMocks() != nil || step.GetMockCallChecks() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func usesBroker(v models.TestInterface) bool {
+	if v.BrokerFixtures() != nil || v.GetBrokerChecks() != nil {
+		return true
+	}
+	for _, step := range v.GetSteps() {
+		if step.GetBrokerChecks() != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,22 @@
 package runner
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lamoda/gonkey/checker"
 	"github.com/lamoda/gonkey/cmd_runner"
+	"github.com/lamoda/gonkey/compare"
 	"github.com/lamoda/gonkey/fixtures"
+	"github.com/lamoda/gonkey/grpc_client"
 	"github.com/lamoda/gonkey/mocks"
 	"github.com/lamoda/gonkey/models"
 	"github.com/lamoda/gonkey/output"
@@ -24,6 +30,18 @@ type Config struct {
 	Mocks          *mocks.Mocks
 	MocksLoader    *mocks.Loader
 	Variables      *variables.Variables
+	// GrpcClient makes the calls of tests with `grpc` section to GrpcHost
+	GrpcClient *grpc_client.Client
+	GrpcHost   string
+	// BrokerFixturesLoader publishes messages of the tests `brokerFixtures` to the message broker
+	BrokerFixturesLoader fixtures.Loader
+	// Parallel is the number of tests executed concurrently,
+	// values less than 2 mean sequential execution
+	Parallel int
+	// Hooks are made before the first and after the last test of the run
+	Hooks *models.Hooks
+	// DB is used by the hooks with SQL statements
+	DB *sql.DB
 }
 
 type Runner struct {
@@ -31,6 +49,16 @@ type Runner struct {
 	output   []output.OutputInterface
 	checkers []checker.CheckerInterface
 
+	// fixtures are loaded one by one even when tests run in parallel
+	fixturesMu sync.Mutex
+
+	hooks *hooksTracker
+
+	// parallelPhase is set while isolation groups are executed concurrently,
+	// the mocks are shared between all tests, so the tests of this phase using them take mocksMu
+	parallelPhase bool
+	mocksMu       sync.Mutex
+
 	config *Config
 }
 
@@ -59,6 +87,49 @@ func (r *Runner) Run() (*models.Summary, error) {
 		return s, nil
 	}
 
+	tests, err := r.loadTests()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.runTests(tests)
+}
+
+// runTests executes the tests along with the hooks of the run
+func (r *Runner) runTests(tests []models.TestInterface) (*models.Summary, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &runStats{}
+
+	r.hooks = newHooksTracker(tests)
+	r.runBeforeAll(client)
+
+	if r.config.Parallel > 1 {
+		err = r.runParallel(tests, client, stats)
+	} else {
+		err = r.runSequential(tests, client, r.config.Variables, stats)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.runAfterAll(client)
+
+	summary := stats.summary()
+	if r.config.Mocks != nil && r.config.Mocks.Strict() {
+		summary.Errors = r.config.Mocks.PendingErrors()
+		if len(summary.Errors) != 0 {
+			summary.Success = false
+		}
+	}
+	return summary, nil
+}
+
+// loadTests loads tests and marks the not focused ones as skipped if there are focused tests
+func (r *Runner) loadTests() ([]models.TestInterface, error) {
 	loader, err := r.loader.Load()
 	if err != nil {
 		return nil, err
@@ -73,18 +144,8 @@ func (r *Runner) Run() (*models.Summary, error) {
 		}
 	}
 
-	client, err := newClient()
-	if err != nil {
-		return nil, err
-	}
-
-	totalTests := 0
-	failedTests := 0
-	skippedTests := 0
-	brokenTests := 0
-
-	for _, v := range tests {
-		if hasFocused {
+	if hasFocused {
+		for _, v := range tests {
 			switch v.GetStatus() {
 			case "focus":
 				v.SetStatus("")
@@ -94,38 +155,114 @@ func (r *Runner) Run() (*models.Summary, error) {
 				v.SetStatus("skipped")
 			}
 		}
+	}
 
-		testResult, err := r.executeTest(v, client)
-		switch {
-		case err != nil && errors.Is(err, errTestSkipped):
-			skippedTests++
-		case err != nil && errors.Is(err, errTestBroken):
-			brokenTests++
-		case err != nil:
-			// todo: populate error with test name. Currently it is not possible here to get test name.
-			return nil, err
-		}
+	return tests, nil
+}
 
-		totalTests++
-		if len(testResult.Errors) > 0 {
-			failedTests++
+type runStats struct {
+	sync.Mutex
+	total   int
+	failed  int
+	skipped int
+	broken  int
+	// hookErrors is the number of the tests broken by failed hooks, they fail the run
+	hookErrors int
+
+	// order of the tests, if it is set the results are passed to the outputs in this order
+	order   map[models.TestInterface]int
+	next    int
+	pending map[int]orderedResult
+}
+
+type orderedResult struct {
+	test   models.TestInterface
+	result *models.Result
+}
+
+// keepOrder makes the results of the tests passed to the outputs in the order of the tests
+func (s *runStats) keepOrder(tests []models.TestInterface) {
+	s.order = make(map[models.TestInterface]int, len(tests))
+	for i, v := range tests {
+		s.order[v] = i
+	}
+	s.pending = make(map[int]orderedResult)
+}
+
+func (s *runStats) summary() *models.Summary {
+	return &models.Summary{
+		Success: s.failed == 0 && s.hookErrors == 0,
+		Skipped: s.skipped,
+		Broken:  s.broken,
+		Failed:  s.failed,
+		Total:   s.total,
+	}
+}
+
+func (r *Runner) runSequential(
+	tests []models.TestInterface,
+	client *http.Client,
+	vars *variables.Variables,
+	stats *runStats,
+) error {
+	for _, v := range tests {
+		testResult, err := r.executeTestWithHooks(v, client, vars)
+		if err := r.processResult(v, testResult, err, stats); err != nil {
+			return err
 		}
-		for _, o := range r.output {
-			if err := o.Process(v, testResult); err != nil {
-				return nil, err
-			}
+		r.testDone(v, client, vars)
+	}
+	return nil
+}
+
+// processResult updates run statistics and passes the result to outputs.
+// It is safe to call it from several goroutines.
+func (r *Runner) processResult(v models.TestInterface, testResult *models.Result, err error, stats *runStats) error {
+	stats.Lock()
+	defer stats.Unlock()
+
+	switch {
+	case err != nil && errors.Is(err, errTestSkipped):
+		stats.skipped++
+	case err != nil && errors.Is(err, errTestBroken):
+		stats.broken++
+		if len(testResult.Errors) != 0 {
+			stats.hookErrors++
 		}
+	case err != nil:
+		// todo: populate error with test name. Currently it is not possible here to get test name.
+		return err
 	}
 
-	s := &models.Summary{
-		Success: failedTests == 0,
-		Skipped: skippedTests,
-		Broken:  brokenTests,
-		Failed:  failedTests,
-		Total:   totalTests,
+	stats.total++
+	if err == nil && len(testResult.Errors) > 0 {
+		stats.failed++
 	}
 
-	return s, nil
+	if stats.order == nil {
+		return r.outputResult(v, testResult)
+	}
+	stats.pending[stats.order[v]] = orderedResult{test: v, result: testResult}
+	for {
+		res, ok := stats.pending[stats.next]
+		if !ok {
+			return nil
+		}
+		delete(stats.pending, stats.next)
+		stats.next++
+		if err := r.outputResult(res.test, res.result); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Runner) outputResult(v models.TestInterface, testResult *models.Result) error {
+	for _, o := range r.output {
+		if err := o.Process(v, testResult); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 var (
@@ -133,7 +270,7 @@ var (
 	errTestBroken  = errors.New("test was broken")
 )
 
-func (r *Runner) executeTest(v models.TestInterface, client *http.Client) (*models.Result, error) {
+func (r *Runner) executeTest(v models.TestInterface, client *http.Client, vars *variables.Variables) (*models.Result, error) {
 
 	if v.GetStatus() != "" {
 		if v.GetStatus() == "broken" {
@@ -145,21 +282,39 @@ func (r *Runner) executeTest(v models.TestInterface, client *http.Client) (*mode
 		}
 	}
 
-	r.config.Variables.Load(v.GetVariables())
-	v = r.config.Variables.Apply(v)
+	startedAt := time.Now()
+
+	vars.Load(v.GetVariables())
+	v = vars.Apply(v)
+
+	// strict mocks are reset before the fixtures are loaded,
+	// so the requests made meanwhile are reported in this test
+	useMocks := r.config.Mocks != nil && (!r.parallelPhase || usesMocks(v))
+	if useMocks && r.parallelPhase {
+		r.mocksMu.Lock()
+		defer r.mocksMu.Unlock()
+	}
+	strictMocks := useMocks && r.config.Mocks.Strict()
+	if strictMocks {
+		r.resetMocks()
+	}
 
 	// load fixtures
 	if r.config.FixturesLoader != nil && v.Fixtures() != nil {
-		if err := r.config.FixturesLoader.Load(v.Fixtures()); err != nil {
+		if err := r.loadFixtures(v.Fixtures()); err != nil {
 			return nil, fmt.Errorf("unable to load fixtures [%s], error:\n%s", strings.Join(v.Fixtures(), ", "), err)
 		}
 	}
 
-	// reset mocks
-	if r.config.Mocks != nil {
-		// prevent deriving the definition from previous test
-		r.config.Mocks.ResetDefinitions()
-		r.config.Mocks.ResetRunningContext()
+	// publish messages to the message broker
+	if r.config.BrokerFixturesLoader != nil && v.BrokerFixtures() != nil {
+		if err := r.config.BrokerFixturesLoader.Load(v.BrokerFixtures()); err != nil {
+			return nil, fmt.Errorf("unable to load broker fixtures [%s], error:\n%s", strings.Join(v.BrokerFixtures(), ", "), err)
+		}
+	}
+
+	if useMocks && !strictMocks {
+		r.resetMocks()
 	}
 
 	// load mocks
@@ -183,10 +338,242 @@ func (r *Runner) executeTest(v models.TestInterface, client *http.Client) (*mode
 		fmt.Printf("Sleep %ds before requests\n", pause)
 	}
 
+	var (
+		result *models.Result
+		err    error
+	)
+	if len(v.GetSteps()) != 0 {
+		result, err = r.executeSteps(v, client, vars)
+	} else if err = r.prepareCheckers(v); err == nil {
+		result, err = r.sendRequest(v, client, vars)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// launch script in cmd interface
+	if v.AfterRequestScriptPath() != "" {
+		if err := cmd_runner.CmdRun(v.AfterRequestScriptPath(), v.AfterRequestScriptTimeout()); err != nil {
+			return nil, err
+		}
+	}
+
+	if useMocks {
+		errs := r.config.Mocks.EndRunningContext()
+		result.Errors = append(result.Errors, errs...)
+		result.MockCalls = r.config.Mocks.Calls()
+	}
+
+	// steps are checked during execution
+	if len(v.GetSteps()) != 0 {
+		v = r.mockVariables(vars).Apply(v)
+		result.Errors = append(result.Errors, checkMockCalls(v, result.MockCalls)...)
+		result.Duration = time.Since(startedAt)
+		return result, nil
+	}
+
+	if err := r.setVariablesFromResponse(v, vars, result.ResponseContentType, result.ResponseBody, result.ResponseStatusCode); err != nil {
+		return nil, err
+	}
+
+	vars.Load(v.GetVariables())
+	v = r.mockVariables(vars).Apply(v)
+
+	if err := r.check(v, result); err != nil {
+		return nil, err
+	}
+	result.Errors = append(result.Errors, checkMockCalls(v, result.MockCalls)...)
+
+	result.Duration = time.Since(startedAt)
+	return result, nil
+}
+
+// resetMocks prepares the mocks for the test
+func (r *Runner) resetMocks() {
+	// prevent deriving the definition from previous test
+	r.config.Mocks.ResetDefinitions()
+	r.config.Mocks.ResetRunningContext()
+}
+
+// prepareCheckers prepares checkers which need it before the request of the test is sent
+func (r *Runner) prepareCheckers(v models.TestInterface) error {
+	for _, c := range r.checkers {
+		if pc, ok := c.(checker.PreparingChecker); ok {
+			if err := pc.Prepare(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Runner) check(v models.TestInterface, result *models.Result) error {
+	if err := checkResponseTime(v, result); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if expected, ok := v.GetExpectedStderr(); ok {
+		for _, err := range compare.Compare(expected, result.Stderr, compare.CompareParams{}) {
+			result.Errors = append(result.Errors, fmt.Errorf("stderr: %w", err))
+		}
+	}
+
+	for _, c := range r.checkers {
+		errs, err := c.Check(v, result)
+		if err != nil {
+			return err
+		}
+		result.Errors = append(result.Errors, errs...)
+	}
+	return nil
+}
+
+// executeSteps executes steps of the scenario one by one until the first failed step.
+// Variables set by the steps are visible to the following steps only.
+func (r *Runner) executeSteps(v models.TestInterface, client *http.Client, vars *variables.Variables) (*models.Result, error) {
+	stepVars := vars.Clone()
+	result := &models.Result{Test: v}
+
+	for _, step := range v.GetSteps() {
+		stepVars.Load(step.GetVariables())
+		step = stepVars.Apply(step)
+
+		if err := r.prepareCheckers(step); err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.GetName(), err)
+		}
+
+		stepResult, err := r.sendRequest(step, client, stepVars)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.GetName(), err)
+		}
+
+		err = r.setVariablesFromResponse(
+			step,
+			stepVars,
+			stepResult.ResponseContentType,
+			stepResult.ResponseBody,
+			stepResult.ResponseStatusCode,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.GetName(), err)
+		}
+
+		stepVars.Load(step.GetVariables())
+		step = r.mockVariables(stepVars).Apply(step)
+		stepResult.Test = step
+
+		if err := r.check(step, stepResult); err != nil {
+			return nil, err
+		}
+
+		result.Steps = append(result.Steps, stepResult)
+
+		// the scenario is described by its last step
+		result.Path = stepResult.Path
+		result.Query = stepResult.Query
+		result.RequestBody = stepResult.RequestBody
+		result.ResponseStatusCode = stepResult.ResponseStatusCode
+		result.ResponseStatus = stepResult.ResponseStatus
+		result.ResponseContentType = stepResult.ResponseContentType
+		result.ResponseBody = stepResult.ResponseBody
+		result.ResponseHeaders = stepResult.ResponseHeaders
+		result.DatabaseResult = append(result.DatabaseResult, stepResult.DatabaseResult...)
+
+		if len(stepResult.Errors) != 0 {
+			for _, e := range stepResult.Errors {
+				result.Errors = append(result.Errors, fmt.Errorf("step %s: %w", step.GetName(), e))
+			}
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// sendRequest sends the request of the test and repeats it according to the retry parameters
+// until it succeeds and the poll condition, if any, is met
+func (r *Runner) sendRequest(v models.TestInterface, client *http.Client, vars *variables.Variables) (*models.Result, error) {
+	params := v.GetRetryParams()
+	attempts := params.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Duration(params.Delay) * time.Second
+
+	var condition models.TestInterface
+	if c := v.GetPollCondition(); c != nil {
+		condition = vars.Apply(c)
+	}
+
+	var attemptsLog []models.Attempt
+	for i := 1; ; i++ {
+		isLast := i >= attempts
+
+		result, err := r.doRequest(v, client)
+		if err != nil {
+			if isLast {
+				return nil, err
+			}
+			attemptsLog = append(attemptsLog, models.Attempt{Errors: []error{err}})
+		} else {
+			var errs []error
+			if condition != nil {
+				errs, err = r.checkPollCondition(condition, result)
+				if err != nil {
+					return nil, err
+				}
+			}
+			// the response with the status listed in retryOn is repeated like a failed request
+			retryStatus := v.GetGrpcRequest() == nil && v.GetScript() == nil && params.RetriesStatus(result.ResponseStatusCode)
+			attempt := models.Attempt{
+				ResponseStatusCode: result.ResponseStatusCode,
+				ResponseBody:       result.ResponseBody,
+				Errors:             errs,
+			}
+			if retryStatus {
+				attempt.Errors = append(attempt.Errors, fmt.Errorf("response status %d is retried", result.ResponseStatusCode))
+			}
+			attemptsLog = append(attemptsLog, attempt)
+
+			if len(errs) == 0 && !retryStatus || isLast {
+				if attempts > 1 {
+					result.Attempts = attemptsLog
+				}
+				if len(errs) != 0 {
+					result.Errors = append(result.Errors, fmt.Errorf("poll condition is not met after %d attempts", i))
+					result.Errors = append(result.Errors, errs...)
+				}
+				return result, nil
+			}
+		}
+
+		time.Sleep(delay)
+		if params.Backoff > 0 {
+			delay = time.Duration(float64(delay) * params.Backoff)
+		}
+	}
+}
+
+func (r *Runner) doRequest(v models.TestInterface, client *http.Client) (*models.Result, error) {
+	ctx := context.Background()
+	if v.RequestTimeout() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(v.RequestTimeout())*time.Second)
+		defer cancel()
+	}
+
+	if v.GetGrpcRequest() != nil {
+		return r.doGrpcRequest(ctx, v)
+	}
+	if v.GetScript() != nil {
+		return r.doScript(v)
+	}
+
 	req, err := newRequest(r.config.Host, v)
 	if err != nil {
 		return nil, err
 	}
+	timings := newTimingsRecorder()
+	req = req.WithContext(timings.withTrace(ctx))
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -201,51 +588,121 @@ func (r *Runner) executeTest(v models.TestInterface, client *http.Client) (*mode
 		return nil, err
 	}
 
-	bodyStr := string(body)
-
-	result := models.Result{
+	result := &models.Result{
 		Path:                req.URL.Path,
 		Query:               req.URL.RawQuery,
 		RequestBody:         actualRequestBody(req),
-		ResponseBody:        bodyStr,
+		ResponseBody:        string(body),
 		ResponseContentType: resp.Header.Get("Content-Type"),
 		ResponseStatusCode:  resp.StatusCode,
 		ResponseStatus:      resp.Status,
 		ResponseHeaders:     resp.Header,
+		Timings:             timings.done(),
 		Test:                v,
 	}
 
-	// launch script in cmd interface
-	if v.AfterRequestScriptPath() != "" {
-		if err := cmd_runner.CmdRun(v.AfterRequestScriptPath(), v.AfterRequestScriptTimeout()); err != nil {
-			return nil, err
-		}
+	return result, nil
+}
+
+// doGrpcRequest makes the gRPC call of the test, the reply or the error status is converted to JSON
+// and the status code is used as the response code, so the usual checkers can be used
+func (r *Runner) doGrpcRequest(ctx context.Context, v models.TestInterface) (*models.Result, error) {
+	if r.config.GrpcClient == nil {
+		return nil, errors.New("gRPC client is not configured")
 	}
 
-	if r.config.Mocks != nil {
-		errs := r.config.Mocks.EndRunningContext()
-		result.Errors = append(result.Errors, errs...)
+	grpcRequest := v.GetGrpcRequest()
+	timings := newTimingsRecorder()
+	resp, err := r.config.GrpcClient.Invoke(
+		ctx,
+		r.config.GrpcHost,
+		grpcRequest.Service,
+		grpcRequest.Method,
+		grpcRequest.Message,
+		grpcRequest.Metadata,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.Result{
+		Path:                fmt.Sprintf("/%s/%s", grpcRequest.Service, grpcRequest.Method),
+		RequestBody:         grpcRequest.Message,
+		ResponseBody:        resp.Message,
+		ResponseContentType: "application/json",
+		ResponseStatusCode:  int(resp.Status.Code()),
+		ResponseStatus:      resp.Status.Code().String(),
+		ResponseHeaders:     resp.Headers,
+		Timings:             timings.done(),
+		Test:                v,
+	}
+
+	return result, nil
+}
+
+// doScript runs the script of the test, the exit code is used as the response code
+// and stdout as the response body, so the usual checkers can be used
+func (r *Runner) doScript(v models.TestInterface) (*models.Result, error) {
+	script := v.GetScript()
+	timeout := script.Timeout
+	if timeout <= 0 {
+		timeout = v.RequestTimeout()
 	}
 
-	if err := r.setVariablesFromResponse(v, result.ResponseContentType, bodyStr, resp.StatusCode); err != nil {
+	timings := newTimingsRecorder()
+	output, err := cmd_runner.Run(cmd_runner.Script{
+		Path:    script.Path,
+		Args:    script.Args,
+		Env:     script.Env,
+		Timeout: timeout,
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	r.config.Variables.Load(v.GetVariables())
-	v = r.config.Variables.Apply(v)
+	contentType := "text/plain"
+	if json.Valid([]byte(output.Stdout)) {
+		contentType = "application/json"
+	}
+
+	result := &models.Result{
+		Path:                script.Path,
+		RequestBody:         strings.Join(script.Args, " "),
+		ResponseBody:        output.Stdout,
+		ResponseContentType: contentType,
+		ResponseStatusCode:  output.ExitCode,
+		ResponseStatus:      fmt.Sprintf("exit code %d", output.ExitCode),
+		Stderr:              output.Stderr,
+		Timings:             timings.done(),
+		Test:                v,
+	}
+
+	return result, nil
+}
+
+// checkPollCondition runs checkers against the poll condition,
+// a copy of the result is used so the checkers don't record anything into the actual result
+func (r *Runner) checkPollCondition(condition models.TestInterface, result *models.Result) ([]error, error) {
+	res := *result
 
+	var errs []error
 	for _, c := range r.checkers {
-		errs, err := c.Check(v, &result)
+		checkErrs, err := c.Check(condition, &res)
 		if err != nil {
 			return nil, err
 		}
-		result.Errors = append(result.Errors, errs...)
+		errs = append(errs, checkErrs...)
 	}
 
-	return &result, nil
+	return errs, nil
 }
 
-func (r *Runner) setVariablesFromResponse(t models.TestInterface, contentType, body string, statusCode int) error {
+func (r *Runner) setVariablesFromResponse(
+	t models.TestInterface,
+	vars *variables.Variables,
+	contentType, body string,
+	statusCode int,
+) error {
 
 	varTemplates := t.GetVariablesToSet()
 	if varTemplates == nil {
@@ -254,16 +711,23 @@ func (r *Runner) setVariablesFromResponse(t models.TestInterface, contentType, b
 
 	isJson := strings.Contains(contentType, "json") && body != ""
 
-	vars, err := variables.FromResponse(varTemplates[statusCode], body, isJson)
+	respVars, err := variables.FromResponse(varTemplates[statusCode], body, isJson)
 	if err != nil {
 		return err
 	}
 
-	if vars == nil {
+	if respVars == nil {
 		return nil
 	}
 
-	r.config.Variables.Merge(vars)
+	vars.Merge(respVars)
 
 	return nil
 }
+
+func (r *Runner) loadFixtures(names []string) error {
+	r.fixturesMu.Lock()
+	defer r.fixturesMu.Unlock()
+
+	return r.config.FixturesLoader.Load(names)
+}
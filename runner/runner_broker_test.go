@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lamoda/gonkey/broker"
+)
+
+func TestBroker(t *testing.T) {
+	b := broker.NewInMemory()
+	srv := httptest.NewServer(brokerHandler(b))
+	defer srv.Close()
+
+	RunWithTesting(t, &RunWithTestingParams{
+		Server:      srv,
+		TestsDir:    filepath.Join("testdata", "broker", "broker.yaml"),
+		FixturesDir: filepath.Join("testdata", "broker", "fixtures"),
+		Broker:      b,
+	})
+}
+
+// brokerHandler returns messages of the `commands` topic and publishes an event per created order
+func brokerHandler(b broker.Broker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/commands", func(w http.ResponseWriter, r *http.Request) {
+		messages, err := b.Messages(r.Context(), "commands")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		type message struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		}
+		var res []message
+		for _, m := range messages {
+			res = append(res, message{Key: m.Key, Value: m.Value})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		var order struct {
+			ID int `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err := b.Publish(context.Background(), "order-events", []broker.Message{{
+			Key:     fmt.Sprint(order.ID),
+			Value:   fmt.Sprintf(`{"id": %d, "status": "created"}`, order.ID),
+			Headers: map[string]string{"type": "OrderCreated"},
+		}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(order)
+	})
+	return mux
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/lamoda/gonkey/grpc_client"
+)
+
+func TestGrpc(t *testing.T) {
+	client, err := grpc_client.New(grpc_client.Config{
+		ProtoFiles:  []string{"greeter.proto"},
+		ImportPaths: []string{filepath.Join("testdata", "grpc")},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	addr := startGreeterServer(t, client)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	RunWithTesting(t, &RunWithTestingParams{
+		Server:     srv,
+		TestsDir:   filepath.Join("testdata", "grpc"),
+		GrpcClient: client,
+		GrpcHost:   addr,
+	})
+}
+
+// startGreeterServer starts the server which implements the service from testdata/grpc/greeter.proto
+func startGreeterServer(t *testing.T, client *grpc_client.Client) string {
+	method, err := client.FindMethod("helloworld.Greeter", "SayHello")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		req := dynamicpb.NewMessage(method.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+
+		if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+			_ = stream.SetHeader(metadata.MD{"x-request-id": md.Get("x-request-id")})
+		}
+
+		name := req.Get(method.Input().Fields().ByName("name")).String()
+		if name == "" {
+			return status.Error(codes.InvalidArgument, "name is required")
+		}
+
+		reply := dynamicpb.NewMessage(method.Output())
+		reply.Set(method.Output().Fields().ByName("message"), protoreflect.ValueOfString("Hello "+name))
+		return stream.SendMsg(reply)
+	}))
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	return ln.Addr().String()
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestHooks(t *testing.T) {
+	var logouts, runStarts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/start":
+			atomic.AddInt32(&runStarts, 1)
+			_, _ = w.Write([]byte(`{"user": "tester"}`))
+		case "/login":
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+		case "/logout":
+			atomic.AddInt32(&logouts, 1)
+		case "/orders":
+			if r.Header.Get("Authorization") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"created": true, "before": "2", "user": "tester"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, title TEXT); INSERT INTO orders (title) VALUES ('a'), ('b')`)
+	require.NoError(t, err)
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+			DB:        db,
+			Hooks: &models.Hooks{
+				BeforeAll: []models.Hook{{
+					Request:        &models.HookRequest{Path: "/start"},
+					VariablesToSet: map[string]string{"runUser": "user"},
+				}},
+			},
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "hooks")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.False(t, summary.Success)
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 2, summary.Broken)
+	assert.Zero(t, summary.Failed)
+
+	require.Len(t, collector.results, 3)
+	assert.Empty(t, collector.results[0].Errors)
+
+	assert.Equal(t, "broken", collector.results[1].Test.GetStatus())
+	require.Len(t, collector.results[1].Errors, 1)
+	assert.Contains(t, collector.results[1].Errors[0].Error(), "afterTest hook #1 failed")
+
+	assert.Equal(t, "broken", collector.results[2].Test.GetStatus())
+	require.Len(t, collector.results[2].Errors, 1)
+	assert.Contains(t, collector.results[2].Errors[0].Error(), "beforeAll hook #1 failed")
+
+	// the own afterTest hook of the second test fails, so the hook of the file is made after the first test only
+	var orders int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM orders").Scan(&orders))
+	assert.Equal(t, 3, orders)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runStarts))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logouts))
+}
+
+func TestHooksBrokenRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+			Hooks: &models.Hooks{
+				BeforeAll: []models.Hook{{Name: "start", Request: &models.HookRequest{Path: "/start"}}},
+			},
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "hooks")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.False(t, summary.Success)
+	assert.Equal(t, 3, summary.Broken)
+	for _, result := range collector.results {
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0].Error(), "beforeAll hook start failed")
+	}
+}
+
+func TestHooksFailedTestWithFailingAfterTest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"created": false}`))
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "hooks-failed")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.False(t, summary.Success)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Zero(t, summary.Broken)
+
+	require.Len(t, collector.results, 1)
+	errs := collector.results[0].Errors
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "values do not match")
+	assert.Contains(t, errs[1].Error(), "afterTest hook #1 failed")
+}
@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestRunLoad(t *testing.T) {
+	var skippedCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"status": "failed"}`))
+		default:
+			atomic.AddInt32(&skippedCalls, 1)
+		}
+	}))
+	defer srv.Close()
+
+	newRunner := func() *Runner {
+		r := New(
+			&Config{
+				Host:      srv.URL,
+				Variables: variables.New(),
+			},
+			yaml_file.NewLoader(filepath.Join("testdata", "load")),
+		)
+		r.AddCheckers(response_body.NewChecker())
+		return r
+	}
+
+	t.Run("concurrency", func(t *testing.T) {
+		summary, err := newRunner().RunLoad(LoadConfig{Duration: 200 * time.Millisecond, Concurrency: 4})
+		require.NoError(t, err)
+
+		require.Greater(t, summary.Requests, 10)
+		assert.Equal(t, summary.Requests, summary.StatusCodes[200]+summary.StatusCodes[500])
+		assert.Equal(t, summary.StatusCodes[500], summary.Errors)
+		assert.InDelta(t, 50, summary.ErrorRate(), 10)
+		assert.Len(t, summary.Latencies, summary.Requests)
+		assert.NotZero(t, summary.Percentile(99))
+	})
+
+	t.Run("rps", func(t *testing.T) {
+		summary, err := newRunner().RunLoad(LoadConfig{Duration: 500 * time.Millisecond, RPS: 20, Concurrency: 2})
+		require.NoError(t, err)
+
+		assert.InDelta(t, 10, summary.Requests, 3)
+		assert.Zero(t, summary.Dropped)
+	})
+
+	assert.Zero(t, atomic.LoadInt32(&skippedCalls))
+}
+
+// queryChecker fails the test if its database checks don't use the value of the response
+type queryChecker struct{}
+
+func (c *queryChecker) Check(t models.TestInterface, result *models.Result) ([]error, error) {
+	expected := "SELECT " + result.ResponseBody[len(`{"n": "`):len(result.ResponseBody)-len(`"}`)]
+	var errs []error
+	if t.DbQueryString() != expected {
+		errs = append(errs, fmt.Errorf("dbQuery %q, expected %q", t.DbQueryString(), expected))
+	}
+	for _, check := range t.GetDatabaseChecks() {
+		if check.DbQueryString() != expected+" AS n" {
+			errs = append(errs, fmt.Errorf("dbChecks query %q, expected %q", check.DbQueryString(), expected+" AS n"))
+		}
+	}
+	return errs, nil
+}
+
+func TestRunLoadShouldApplyVariablesToCopyOfTest(t *testing.T) {
+	var counter int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"n": "%d"}`, atomic.AddInt32(&counter, 1))
+	}))
+	defer srv.Close()
+
+	m := mocks.NewNop("backend")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	r := New(
+		&Config{
+			Host:        srv.URL,
+			Variables:   variables.New(),
+			Mocks:       m,
+			MocksLoader: mocks.NewLoader(m),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "load-state")),
+	)
+	r.AddCheckers(response_body.NewChecker())
+	r.AddCheckers(&queryChecker{})
+
+	summary, err := r.RunLoad(LoadConfig{Duration: 200 * time.Millisecond, Concurrency: 4})
+	require.NoError(t, err)
+
+	require.Greater(t, summary.Requests, 10)
+	assert.Zero(t, summary.Errors)
+}
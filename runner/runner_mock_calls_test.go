@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestMockCalls(t *testing.T) {
+	m := mocks.NewNop("orders", "stock")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var item struct {
+			Sku string `json:"sku"`
+		}
+		_ = json.Unmarshal(body, &item)
+
+		resp, err := http.Get("http://" + m.Service("stock").ServerAddr() + "/reserve?sku=" + item.Sku)
+		require.NoError(t, err)
+		reserved, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		resp, err = http.Post("http://"+m.Service("orders").ServerAddr()+"/orders", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		var order struct {
+			ID int `json:"id"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&order)
+		resp.Body.Close()
+
+		reservedJSON, _ := json.Marshal(string(reserved))
+		fmt.Fprintf(w, `{"order": %d, "reserved": %s}`, order.ID, reservedJSON)
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:        srv.URL,
+			Mocks:       m,
+			MocksLoader: mocks.NewLoader(m),
+			Variables:   variables.New(),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "mock-calls")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Failed)
+
+	require.Len(t, collector.results, 2)
+	assert.Empty(t, collector.results[0].Errors)
+	assert.Len(t, collector.results[0].MockCalls, 2)
+
+	errs := collector.results[1].Errors
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "mock orders, request #1")
+	assert.Contains(t, errs[1].Error(), "mock stock: request GET /reserve was received before the previous expected request")
+}
+
+func TestStrictMocksRequestAfterLastTest(t *testing.T) {
+	m := mocks.NewNop("stock")
+	m.SetStrict(true)
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/late" {
+			resp, err := http.Get("http://" + m.Service("stock").ServerAddr() + "/reserve")
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:        srv.URL,
+			Mocks:       m,
+			MocksLoader: mocks.NewLoader(m),
+			Variables:   variables.New(),
+			// the hook of the run makes the mock receive the request after the last test
+			Hooks: &models.Hooks{
+				AfterAll: []models.Hook{{Request: &models.HookRequest{Path: "/late"}}},
+			},
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "strict-mocks")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	require.Len(t, collector.results, 1)
+	assert.Empty(t, collector.results[0].Errors)
+
+	assert.False(t, summary.Success)
+	require.Len(t, summary.Errors, 1)
+	assert.Contains(t, summary.Errors[0].Error(), "mock stock: unexpected request to mock, received between tests:\nGET /reserve")
+}
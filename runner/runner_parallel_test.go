@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/models"
+	testingOutput "github.com/lamoda/gonkey/output/testing"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestParallel(t *testing.T) {
+	var current, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+
+		if r.URL.Path == "/concurrency" {
+			_, _ = fmt.Fprintf(w, "%d", n)
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"id": "%s"}`, r.URL.Query().Get("id"))
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+			Parallel:  4,
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "parallel")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(testingOutput.NewOutput(t), collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+
+	assert.True(t, summary.Success)
+	assert.Equal(t, 8, summary.Total)
+	assert.Greater(t, int(atomic.LoadInt32(&max)), 1)
+
+	// the results are reported in the order of the files
+	var names []string
+	for _, result := range collector.results {
+		names = append(names, result.Test.GetName())
+	}
+	assert.Equal(t, []string{
+		"parallel: first test of group a",
+		"parallel: second test of group a sees variables of its group",
+		"parallel: first test of group b",
+		"parallel: second test of group b sees variables of its group",
+		"parallel: first test of explicit isolation group",
+		"parallel: not parallel test runs alone",
+		"parallel: second test of explicit isolation group",
+		"parallel: not parallel test sees variables of the groups",
+	}, names)
+}
+
+func TestGroupTests(t *testing.T) {
+	tests, err := yaml_file.NewLoader(filepath.Join("testdata", "parallel-grouping")).Load()
+	require.NoError(t, err)
+
+	var all []models.TestInterface
+	for v := range tests {
+		all = append(all, v)
+	}
+	require.Len(t, all, 4)
+
+	parallel, serial := groupTests(all)
+	require.Len(t, parallel, 3)
+	for i, name := range []string{
+		"grouping: plain test",
+		"grouping: fixtures with isolation group",
+		"grouping: mock calls check",
+	} {
+		require.Len(t, parallel[i].tests, 1)
+		assert.Equal(t, name, parallel[i].tests[0].GetName())
+	}
+
+	// fixtures of the file may truncate tables used by other files
+	require.Len(t, serial, 1)
+	assert.Equal(t, "grouping: fixtures without isolation group", serial[0].GetName())
+}
+
+func TestParallelWithMocks(t *testing.T) {
+	m := mocks.NewNop("backend")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+	m.SetStrict(true)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/backend" {
+			resp, err := http.Get("http://" + m.Service("backend").ServerAddr() + "/items")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			_, _ = io.Copy(w, resp.Body)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:        srv.URL,
+			Mocks:       m,
+			MocksLoader: mocks.NewLoader(m),
+			Variables:   variables.New(),
+			Parallel:    2,
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "parallel-mocks")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.True(t, summary.Success)
+	require.Len(t, collector.results, 4)
+
+	// the tests using the mocks take turns to reset and check them
+	for _, result := range collector.results {
+		if strings.HasSuffix(result.Test.GetName(), "test with mocks") {
+			assert.Empty(t, result.Errors)
+			assert.Len(t, result.MockCalls, 1)
+		} else {
+			assert.Nil(t, result.MockCalls)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestMaxResponseTime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "response-time")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.False(t, summary.Success)
+	assert.Equal(t, 1, summary.Failed)
+
+	require.Len(t, collector.results, 2)
+
+	fast := collector.results[0]
+	assert.Empty(t, fast.Errors)
+	assert.NotZero(t, fast.Timings.Connect)
+	assert.NotZero(t, fast.Timings.TimeToFirstByte)
+	assert.GreaterOrEqual(t, int64(fast.Timings.Total), int64(fast.Timings.TimeToFirstByte))
+
+	slow := collector.results[1]
+	require.Len(t, slow.Errors, 1)
+	assert.Contains(t, slow.Errors[0].Error(), "exceeds maxResponseTime 50ms")
+	assert.GreaterOrEqual(t, int64(slow.Timings.TimeToFirstByte), int64(200*time.Millisecond))
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+type resultsCollector struct {
+	results []*models.Result
+}
+
+func (o *resultsCollector) Process(t models.TestInterface, result *models.Result) error {
+	o.results = append(o.results, result)
+	return nil
+}
+
+func TestRetry(t *testing.T) {
+	var jobCalls, slowCalls, unavailableCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/job":
+			if atomic.AddInt32(&jobCalls, 1) < 3 {
+				_, _ = w.Write([]byte(`{"status": "pending"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status": "done"}`))
+		case "/slow":
+			if atomic.AddInt32(&slowCalls, 1) == 1 {
+				time.Sleep(2 * time.Second)
+			}
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		case "/unavailable":
+			if atomic.AddInt32(&unavailableCalls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		}
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "retry")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.True(t, summary.Success)
+
+	require.Len(t, collector.results, 3)
+	assert.Empty(t, collector.results[0].Errors)
+	assert.Len(t, collector.results[0].Attempts, 3)
+	assert.Empty(t, collector.results[1].Errors)
+	assert.Len(t, collector.results[1].Attempts, 2)
+	assert.Empty(t, collector.results[2].Errors)
+	require.Len(t, collector.results[2].Attempts, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, collector.results[2].Attempts[0].ResponseStatusCode)
+}
@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestScript(t *testing.T) {
+	r := New(
+		&Config{
+			Variables: variables.New(),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "script")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.False(t, summary.Success)
+	assert.Equal(t, 1, summary.Failed)
+
+	require.Len(t, collector.results, 3)
+
+	scenario := collector.results[0]
+	assert.Empty(t, scenario.Errors)
+	require.Len(t, scenario.Steps, 2)
+	assert.Equal(t, "application/json", scenario.Steps[0].ResponseContentType)
+	assert.Equal(t, "count 31", scenario.Steps[1].RequestBody)
+
+	failed := collector.results[1]
+	assert.Empty(t, failed.Errors)
+	assert.Equal(t, 2, failed.ResponseStatusCode)
+	assert.Equal(t, "cannot connect\n", failed.Stderr)
+
+	unexpected := collector.results[2]
+	require.Len(t, unexpected.Errors, 1)
+	assert.Contains(t, unexpected.Errors[0].Error(), "stderr:")
+}
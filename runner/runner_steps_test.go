@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+func TestSteps(t *testing.T) {
+	var echoed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/items":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": "42"}`))
+		case strings.HasPrefix(r.URL.Path, "/items/"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"id": "%s", "name": "book"}`, strings.TrimPrefix(r.URL.Path, "/items/"))
+		case r.URL.Path == "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			echoed = string(body)
+			_, _ = w.Write(body)
+		}
+	}))
+	defer srv.Close()
+
+	r := New(
+		&Config{
+			Host:      srv.URL,
+			Variables: variables.New(),
+		},
+		yaml_file.NewLoader(filepath.Join("testdata", "steps")),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+	r.AddCheckers(response_body.NewChecker())
+
+	summary, err := r.Run()
+	require.NoError(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, 2, summary.Total)
+
+	require.Len(t, collector.results, 2)
+	require.Len(t, collector.results[0].Steps, 2)
+	assert.Equal(t, "get", collector.results[0].Steps[1].Test.GetName())
+	assert.Equal(t, "/items/42", collector.results[0].Steps[1].Path)
+
+	// the variable set by the step is not substituted in the next test
+	assert.Equal(t, "{{ $itemId }}", echoed)
+}
@@ -5,20 +5,30 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/aerospike/aerospike-client-go/v5"
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/lamoda/gonkey/broker"
 	"github.com/lamoda/gonkey/checker"
 	"github.com/lamoda/gonkey/checker/response_body"
+	"github.com/lamoda/gonkey/checker/response_broker"
 	"github.com/lamoda/gonkey/checker/response_db"
 	"github.com/lamoda/gonkey/checker/response_header"
+	"github.com/lamoda/gonkey/checker/response_mongo"
 	"github.com/lamoda/gonkey/fixtures"
+	brokerFixtures "github.com/lamoda/gonkey/fixtures/broker"
+	"github.com/lamoda/gonkey/grpc_client"
 	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/models"
 	"github.com/lamoda/gonkey/output"
 	"github.com/lamoda/gonkey/output/allure_report"
+	"github.com/lamoda/gonkey/output/junit_report"
 	testingOutput "github.com/lamoda/gonkey/output/testing"
 	aerospikeAdapter "github.com/lamoda/gonkey/storage/aerospike"
+	mongoAdapter "github.com/lamoda/gonkey/storage/mongo"
 	"github.com/lamoda/gonkey/testloader/yaml_file"
 	"github.com/lamoda/gonkey/variables"
 )
@@ -35,12 +45,25 @@ type RunWithTestingParams struct {
 	FixturesDir   string
 	DB            *sql.DB
 	Aerospike     Aerospike
+	// Mongo is used for database checks with `mongoQuery` and for fixtures when DbType is fixtures.Mongo
+	Mongo *mongo.Database
 	// If DB parameter present, used to recognize type of database, if not set, by default uses Postgres
 	DbType        fixtures.DbType
 	EnvFilePath   string
 	OutputFunc    output.OutputInterface
 	Checkers      []checker.CheckerInterface
 	FixtureLoader fixtures.Loader
+	// GrpcClient is used for the tests with `grpc` section, calls are made to GrpcHost
+	GrpcClient *grpc_client.Client
+	GrpcHost   string
+	// Broker is used for the tests with `brokerFixtures` and `brokerChecks`,
+	// broker fixtures are loaded from FixturesDir
+	Broker broker.Broker
+	// BrokerTimeout limits the time of subscribing to the checked topics and reading their messages,
+	// response_broker.DefaultTimeout is used if it is not set
+	BrokerTimeout time.Duration
+	// Hooks are made before the first and after the last test of the run, see yaml_file.ParseHooksFile
+	Hooks *models.Hooks
 }
 
 // RunWithTesting is a helper function the wraps the common Run and provides simple way
@@ -60,40 +83,59 @@ func RunWithTesting(t *testing.T, params *RunWithTestingParams) {
 	debug := os.Getenv("GONKEY_DEBUG") != ""
 
 	var fixturesLoader fixtures.Loader
-	if params.DB != nil || params.Aerospike.Client != nil || params.FixtureLoader != nil  {
+	if params.DB != nil || params.Aerospike.Client != nil || params.Mongo != nil || params.FixtureLoader != nil {
 		fixturesLoader = fixtures.NewLoader(&fixtures.Config{
 			Location:      params.FixturesDir,
 			DB:            params.DB,
 			Aerospike:     aerospikeAdapter.New(params.Aerospike.Client, params.Aerospike.Namespace),
+			Mongo:         mongoAdapter.New(params.Mongo),
 			Debug:         debug,
 			DbType:        params.DbType,
 			FixtureLoader: params.FixtureLoader,
 		})
 	}
 
-	runner := initRunner(params, mocksLoader, fixturesLoader)
+	var brokerFixturesLoader fixtures.Loader
+	if params.Broker != nil {
+		brokerFixturesLoader = brokerFixtures.New(params.Broker, params.FixturesDir, debug)
+	}
+
+	runner := initRunner(params, mocksLoader, fixturesLoader, brokerFixturesLoader)
 
 	setupOutputs(runner, params, t)
 
 	addCheckers(runner, params)
 
-	_, err := runner.Run()
+	summary, err := runner.Run()
 	if err != nil {
 		t.Fatal(err)
 	}
+	for _, err := range summary.Errors {
+		t.Error(err)
+	}
 }
 
-func initRunner(params *RunWithTestingParams, mocksLoader *mocks.Loader, fixturesLoader fixtures.Loader) *Runner {
+func initRunner(
+	params *RunWithTestingParams,
+	mocksLoader *mocks.Loader,
+	fixturesLoader fixtures.Loader,
+	brokerFixturesLoader fixtures.Loader,
+) *Runner {
 	yamlLoader := yaml_file.NewLoader(params.TestsDir)
 	yamlLoader.SetFileFilter(os.Getenv("GONKEY_FILE_FILTER"))
 
 	runner := New(
 		&Config{
-			Host:           params.Server.URL,
-			Mocks:          params.Mocks,
-			MocksLoader:    mocksLoader,
-			FixturesLoader: fixturesLoader,
-			Variables:      variables.New(),
+			Host:                 params.Server.URL,
+			Mocks:                params.Mocks,
+			MocksLoader:          mocksLoader,
+			FixturesLoader:       fixturesLoader,
+			BrokerFixturesLoader: brokerFixturesLoader,
+			Variables:            variables.New(),
+			GrpcClient:           params.GrpcClient,
+			GrpcHost:             params.GrpcHost,
+			Hooks:                params.Hooks,
+			DB:                   params.DB,
 		},
 		yamlLoader,
 	)
@@ -105,7 +147,15 @@ func addCheckers(runner *Runner, params *RunWithTestingParams) {
 	runner.AddCheckers(response_header.NewChecker())
 
 	if params.DB != nil {
-		runner.AddCheckers(response_db.NewChecker(params.DB))
+		runner.AddCheckers(response_db.NewCheckerForDbType(params.DB, dbTypeParam(params.DbType)))
+	}
+
+	if params.Mongo != nil {
+		runner.AddCheckers(response_mongo.NewChecker(params.Mongo))
+	}
+
+	if params.Broker != nil {
+		runner.AddCheckers(response_broker.NewCheckerWithTimeout(params.Broker, params.BrokerTimeout))
 	}
 
 	runner.AddCheckers(params.Checkers...)
@@ -123,4 +173,26 @@ func setupOutputs(r *Runner, params *RunWithTestingParams, t *testing.T) {
 		defer allureOutput.Finalize()
 		r.AddOutput(allureOutput)
 	}
+
+	if os.Getenv("GONKEY_JUNIT_REPORT") != "" {
+		junitOutput := junit_report.NewOutput("Gonkey", os.Getenv("GONKEY_JUNIT_REPORT"))
+		t.Cleanup(func() {
+			if err := junitOutput.Finalize(); err != nil {
+				t.Error(err)
+			}
+		})
+		r.AddOutput(junitOutput)
+	}
+}
+
+// dbTypeParam returns the name of the SQL database type used by the DB checker
+func dbTypeParam(dbType fixtures.DbType) string {
+	switch dbType {
+	case fixtures.Mysql:
+		return fixtures.MysqlParam
+	case fixtures.Sqlite:
+		return fixtures.SqliteParam
+	default:
+		return fixtures.PostgresParam
+	}
 }
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/mocks"
+	"github.com/lamoda/gonkey/models"
+	"github.com/lamoda/gonkey/testloader/yaml_file"
+	"github.com/lamoda/gonkey/variables"
+)
+
+type fixturesLoaderMock struct{}
+
+func (fixturesLoaderMock) Load([]string) error { return nil }
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	// the modification time may be not updated for the writes within the same tick of the clock
+	mtime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+func TestWatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "gonkey-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testsDir := filepath.Join(dir, "tests")
+	fixturesDir := filepath.Join(dir, "fixtures")
+	mocksDir := filepath.Join(dir, "mocks")
+	writeFile(t, filepath.Join(testsDir, "orders.yaml"), `
+- name: orders
+  method: GET
+  path: /orders
+  fixtures: [orders]
+  response:
+    200: ""
+`)
+	writeFile(t, filepath.Join(testsDir, "users.yaml"), `
+- name: users
+  method: GET
+  path: /users
+  fixtures: [users.yml]
+  response:
+    200: ""
+`)
+	writeFile(t, filepath.Join(testsDir, "stock.yaml"), `
+- name: stock
+  method: GET
+  path: /stock
+  mocks:
+    stock:
+      strategy: file
+      filename: `+filepath.Join(mocksDir, "stock.json")+`
+  response:
+    200: ""
+`)
+	writeFile(t, filepath.Join(mocksDir, "stock.json"), `{"items": []}`)
+	writeFile(t, filepath.Join(fixturesDir, "common.yml"), "tables: {}\n")
+	writeFile(t, filepath.Join(fixturesDir, "orders.yml"), "inherits: [common]\n")
+	writeFile(t, filepath.Join(fixturesDir, "users.yml"), "tables: {}\n")
+
+	m := mocks.NewNop("stock")
+	require.NoError(t, m.Start())
+	defer m.Shutdown()
+
+	r := New(
+		&Config{
+			Host:           srv.URL,
+			FixturesLoader: fixturesLoaderMock{},
+			Mocks:          m,
+			MocksLoader:    mocks.NewLoader(m),
+			Variables:      variables.New(),
+		},
+		yaml_file.NewLoader(testsDir),
+	)
+	collector := &resultsCollector{}
+	r.AddOutput(collector)
+
+	runs := make(chan *models.Summary, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Watch(ctx, WatchConfig{
+			TestsLocation:    testsDir,
+			FixturesLocation: fixturesDir,
+			MocksLocation:    mocksDir,
+			Interval:         10 * time.Millisecond,
+		}, func(summary *models.Summary) {
+			runs <- summary
+		})
+	}()
+
+	nextRun := func() []string {
+		t.Helper()
+		select {
+		case summary := <-runs:
+			assert.True(t, summary.Success)
+			var names []string
+			for _, result := range collector.results[len(collector.results)-summary.Total:] {
+				names = append(names, result.Test.GetName())
+			}
+			return names
+		case <-time.After(5 * time.Second):
+			t.Fatal("tests are not run")
+			return nil
+		}
+	}
+
+	assert.Equal(t, []string{"orders", "stock", "users"}, nextRun())
+
+	writeFile(t, filepath.Join(testsDir, "users.yaml"), `
+- name: users changed
+  method: GET
+  path: /users
+  response:
+    200: ""
+`)
+	assert.Equal(t, []string{"users changed"}, nextRun())
+
+	// orders fixture inherits the common one
+	writeFile(t, filepath.Join(fixturesDir, "common.yml"), "tables:\n  orders: []\n")
+	assert.Equal(t, []string{"orders"}, nextRun())
+
+	writeFile(t, filepath.Join(mocksDir, "stock.json"), `{"items": [1]}`)
+	assert.Equal(t, []string{"stock"}, nextRun())
+
+	cancel()
+	require.NoError(t, <-done)
+}
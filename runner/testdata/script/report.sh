@@ -0,0 +1,11 @@
+#!/bin/sh
+
+case "$1" in
+count)
+  echo "{\"count\": $2, \"mode\": \"$REPORT_MODE\"}"
+  ;;
+fail)
+  echo "cannot $2" >&2
+  exit 2
+  ;;
+esac
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+// timingsRecorder measures phases of the HTTP request
+type timingsRecorder struct {
+	sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      models.Timings
+}
+
+func newTimingsRecorder() *timingsRecorder {
+	return &timingsRecorder{start: time.Now()}
+}
+
+// withTrace returns the context which makes the request report its phases to the recorder
+func (t *timingsRecorder) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.timings.DNSLookup = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.Lock()
+			defer t.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.Lock()
+			defer t.Unlock()
+			t.timings.Connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.Lock()
+			defer t.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.Lock()
+			defer t.Unlock()
+			t.timings.TLSHandshake = time.Since(t.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			t.Lock()
+			defer t.Unlock()
+			t.timings.TimeToFirstByte = time.Since(t.start)
+		},
+	})
+}
+
+// done returns the timings, the total time is measured up to the call
+func (t *timingsRecorder) done() models.Timings {
+	t.Lock()
+	defer t.Unlock()
+	t.timings.Total = time.Since(t.start)
+	return t.timings
+}
+
+// checkResponseTime returns an error if the response took longer than allowed by the test
+func checkResponseTime(v models.TestInterface, result *models.Result) error {
+	if v.MaxResponseTime() <= 0 {
+		return nil
+	}
+	maxResponseTime := time.Duration(v.MaxResponseTime()) * time.Millisecond
+	if result.Timings.Total > maxResponseTime {
+		return fmt.Errorf(
+			"response time %s exceeds maxResponseTime %s",
+			result.Timings.Total.Round(time.Millisecond),
+			maxResponseTime,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+// WatchConfig describes the files watched for changes
+type WatchConfig struct {
+	// TestsLocation is the file or directory of the tests, the changed test files are re-run
+	TestsLocation string
+	// FixturesLocation is the directory of the fixtures, the tests which load the changed fixtures
+	// directly or through `inherits` are re-run
+	FixturesLocation string
+	// MocksLocation is the file or directory of the files used by `file` mock strategy,
+	// the tests which mocks reply with the changed files are re-run
+	MocksLocation string
+	// Interval between checks of the files, 500ms by default
+	Interval time.Duration
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch runs all the tests and then re-runs the tests affected by changes of the watched files
+// until the context is done. The mocks of the runner are not stopped between the runs.
+// onRun is called with the summary of every run, errors of the runs are printed and don't stop the watch.
+func (r *Runner) Watch(ctx context.Context, cfg WatchConfig, onRun func(*models.Summary)) error {
+	if r.loader == nil {
+		return nil
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	locations := []string{cfg.TestsLocation, cfg.FixturesLocation, cfg.MocksLocation}
+	snapshot := scanFiles(locations)
+
+	if summary, err := r.Run(); err != nil {
+		fmt.Printf("Gonkey: %s\n", err)
+	} else {
+		onRun(summary)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current := scanFiles(locations)
+		changed := changedFiles(snapshot, current)
+		snapshot = current
+		if len(changed) == 0 {
+			continue
+		}
+
+		tests, err := r.loadTests()
+		if err != nil {
+			fmt.Printf("Gonkey: %s\n", err)
+			continue
+		}
+		tests = affectedTests(tests, changed, cfg)
+		if len(tests) == 0 {
+			fmt.Printf("Gonkey: %s changed, no tests affected\n", strings.Join(changed, ", "))
+			continue
+		}
+
+		fmt.Printf("Gonkey: %s changed, running %d tests\n", strings.Join(changed, ", "), len(tests))
+		summary, err := r.runTests(tests)
+		if err != nil {
+			fmt.Printf("Gonkey: %s\n", err)
+			continue
+		}
+		onRun(summary)
+	}
+}
+
+// scanFiles returns the states of the files in the locations, the missing locations are skipped
+func scanFiles(locations []string) map[string]fileState {
+	files := make(map[string]fileState)
+	for _, location := range locations {
+		if location == "" {
+			continue
+		}
+		_ = filepath.Walk(location, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			files[filepath.Clean(path)] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return files
+}
+
+// changedFiles returns the sorted list of the created and modified files, the removed files are not included
+func changedFiles(before, after map[string]fileState) []string {
+	var changed []string
+	for path, state := range after {
+		if prev, ok := before[path]; !ok || prev != state {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// affectedTests returns the tests of the changed files and the tests which depend on the changed fixtures or mock files
+func affectedTests(tests []models.TestInterface, changed []string, cfg WatchConfig) []models.TestInterface {
+	changedTests := make(map[string]bool)
+	changedMocks := make(map[string]bool)
+	var changedFixtures []string
+	for _, path := range changed {
+		switch {
+		case isInLocation(path, cfg.FixturesLocation):
+			changedFixtures = append(changedFixtures, fixtureName(cfg.FixturesLocation, path))
+		case isInLocation(path, cfg.MocksLocation):
+			changedMocks[absPath(path)] = true
+		default:
+			changedTests[path] = true
+		}
+	}
+	fixtures := inheritingFixtures(cfg.FixturesLocation, changedFixtures)
+
+	var affected []models.TestInterface
+	for _, v := range tests {
+		if changedTests[filepath.Clean(v.GetFileName())] ||
+			usesFixtures(v, fixtures) ||
+			usesMockFiles(v, changedMocks) {
+			affected = append(affected, v)
+		}
+	}
+	return affected
+}
+
+func isInLocation(path, location string) bool {
+	if location == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(location), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// fixtureName returns the name the fixture file is referred by in the tests and in `inherits`
+func fixtureName(location, path string) string {
+	name, err := filepath.Rel(filepath.Clean(location), path)
+	if err != nil {
+		name = path
+	}
+	return trimFixtureExt(filepath.ToSlash(name))
+}
+
+func trimFixtureExt(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".yml"), ".yaml")
+}
+
+// inheritingFixtures returns the names of the fixtures along with all the fixtures which inherit them
+func inheritingFixtures(location string, names []string) map[string]bool {
+	res := make(map[string]bool)
+	if len(names) == 0 {
+		return res
+	}
+
+	// inheritedBy maps the fixture to the fixtures which inherit it
+	inheritedBy := make(map[string][]string)
+	_ = filepath.Walk(location, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var f struct {
+			Inherits []string `yaml:"inherits"`
+		}
+		if yaml.Unmarshal(data, &f) != nil {
+			return nil
+		}
+		name := fixtureName(location, path)
+		for _, parent := range f.Inherits {
+			parent = trimFixtureExt(parent)
+			inheritedBy[parent] = append(inheritedBy[parent], name)
+		}
+		return nil
+	})
+
+	queue := names
+	for len(queue) != 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if res[name] {
+			continue
+		}
+		res[name] = true
+		queue = append(queue, inheritedBy[name]...)
+	}
+	return res
+}
+
+func usesFixtures(v models.TestInterface, fixtures map[string]bool) bool {
+	if len(fixtures) == 0 {
+		return false
+	}
+	for _, names := range [][]string{v.Fixtures(), v.BrokerFixtures()} {
+		for _, name := range names {
+			if fixtures[trimFixtureExt(name)] {
+				return true
+			}
+		}
+	}
+	for _, step := range v.GetSteps() {
+		if usesFixtures(step, fixtures) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesMockFiles checks `filename` of the mocks definitions of the test
+func usesMockFiles(v models.TestInterface, files map[string]bool) bool {
+	if len(files) == 0 {
+		return false
+	}
+	for _, definition := range v.ServiceMocks() {
+		if mockDefinitionUsesFiles(definition, files) {
+			return true
+		}
+	}
+	for _, step := range v.GetSteps() {
+		if usesMockFiles(step, files) {
+			return true
+		}
+	}
+	return false
+}
+
+func mockDefinitionUsesFiles(definition interface{}, files map[string]bool) bool {
+	switch d := definition.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range d {
+			if filename, ok := v.(string); ok && k == "filename" && files[absPath(filename)] {
+				return true
+			}
+			if mockDefinitionUsesFiles(v, files) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range d {
+			if mockDefinitionUsesFiles(v, files) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Client struct {
+	db *mongo.Database
+}
+
+func New(db *mongo.Database) *Client {
+	return &Client{
+		db: db,
+	}
+}
+
+func (c *Client) Truncate(collection string) error {
+	_, err := c.db.Collection(collection).DeleteMany(context.Background(), bson.D{})
+	return err
+}
+
+func (c *Client) InsertDocuments(collection string, documents []interface{}) ([]interface{}, error) {
+	res, err := c.db.Collection(collection).InsertMany(context.Background(), documents)
+	if err != nil {
+		return nil, err
+	}
+	return res.InsertedIDs, nil
+}
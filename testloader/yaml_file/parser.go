@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"text/template"
 
 	"github.com/lamoda/gonkey/models"
@@ -16,19 +17,30 @@ func parseTestDefinitionFile(absPath string) ([]Test, error) {
 		return nil, fmt.Errorf("failed to read file %s:\n%s", absPath, err)
 	}
 
-	var testDefinitions []TestDefinition
+	var file fileDefinition
 
 	// reading the test source file
-	if err := yaml.Unmarshal(data, &testDefinitions); err != nil {
+	if err := yaml.Unmarshal(data, &file); err != nil {
 		return nil, fmt.Errorf("failed to unmarshall %s:\n%s", absPath, err)
 	}
 
+	var fileHooks *models.Hooks
+	if file.BeforeAll != nil || file.AfterAll != nil {
+		fileHooks = &models.Hooks{BeforeAll: file.BeforeAll, AfterAll: file.AfterAll}
+	}
+
 	var tests []Test
 
-	for _, definition := range testDefinitions {
+	for _, definition := range file.Tests {
+		if file.AfterTest != nil {
+			definition.AfterTestHooks = append(definition.AfterTestHooks, file.AfterTest...)
+		}
 		if testCases, err := makeTestFromDefinition(absPath, definition); err != nil {
 			return nil, err
 		} else {
+			for i := range testCases {
+				testCases[i].FileHooks = fileHooks
+			}
 			tests = append(tests, testCases...)
 		}
 	}
@@ -36,6 +48,42 @@ func parseTestDefinitionFile(absPath string) ([]Test, error) {
 	return tests, nil
 }
 
+// fileDefinition is the content of the test file: either the list of tests
+// or the mapping with the tests and hooks of the file
+type fileDefinition struct {
+	BeforeAll []models.Hook    `yaml:"beforeAll"`
+	AfterAll  []models.Hook    `yaml:"afterAll"`
+	AfterTest []models.Hook    `yaml:"afterTest"`
+	Tests     []TestDefinition `yaml:"tests"`
+}
+
+func (f *fileDefinition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if _, ok := raw.(map[interface{}]interface{}); !ok {
+		return unmarshal(&f.Tests)
+	}
+
+	type plain fileDefinition
+	return unmarshal((*plain)(f))
+}
+
+// ParseHooksFile reads hooks of the whole run
+func ParseHooksFile(path string) (*models.Hooks, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s:\n%s", path, err)
+	}
+
+	var hooks models.Hooks
+	if err := yaml.UnmarshalStrict(data, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall %s:\n%s", path, err)
+	}
+	return &hooks, nil
+}
+
 func substituteArgs(tmpl string, args map[string]interface{}) (string, error) {
 	compiledTmpl, err := template.New("").Parse(tmpl)
 	if err != nil {
@@ -63,10 +111,60 @@ func substituteArgsToMap(tmpl map[string]string, args map[string]interface{}) (m
 	return res, nil
 }
 
+func substituteArgsToMongoQuery(tmpl *models.MongoQuery, args map[string]interface{}) (*models.MongoQuery, error) {
+	if tmpl == nil {
+		return nil, nil
+	}
+	res := *tmpl
+	for _, field := range []*string{&res.Collection, &res.Find, &res.Sort, &res.Aggregate} {
+		var err error
+		*field, err = substituteArgs(*field, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &res, nil
+}
+
+func substituteArgsToScript(tmpl models.ScriptRequest, args map[string]interface{}) (*models.ScriptRequest, error) {
+	res := tmpl
+	var err error
+	res.Path, err = substituteArgs(tmpl.Path, args)
+	if err != nil {
+		return nil, err
+	}
+	res.Args = make([]string, len(tmpl.Args))
+	for i, arg := range tmpl.Args {
+		res.Args[i], err = substituteArgs(arg, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res.Env, err = substituteArgsToMap(tmpl.Env, args)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 // Make tests from the given test definition.
 func makeTestFromDefinition(filePath string, testDefinition TestDefinition) ([]Test, error) {
 	var tests []Test
 
+	// the request is sent once without retries, so the condition would never be polled
+	if testDefinition.PollUntil != nil && testDefinition.RetryParams.Attempts < 2 {
+		return nil, fmt.Errorf("test %s: pollUntil requires retry with more than one attempt", testDefinition.Name)
+	}
+	for _, status := range testDefinition.RetryParams.RetryOn {
+		if !models.ValidRetryStatus(status) {
+			return nil, fmt.Errorf("test %s: retryOn must list status codes or their classes like 5xx, got %s", testDefinition.Name, status)
+		}
+	}
+
+	if len(testDefinition.StepDefinitions) != 0 {
+		return makeScenarioFromDefinition(filePath, testDefinition)
+	}
+
 	// test definition has no cases, so using request/response as is
 	if len(testDefinition.Cases) == 0 {
 		test := Test{TestDefinition: testDefinition, Filename: filePath}
@@ -80,10 +178,25 @@ func makeTestFromDefinition(filePath string, testDefinition TestDefinition) ([]T
 
 		dbChecks := []models.DatabaseCheck{}
 		for _, check := range testDefinition.DatabaseChecks {
-			dbChecks = append(dbChecks, &dbCheck{query: check.DbQueryTmpl, response: check.DbResponseTmpl})
+			dbChecks = append(dbChecks, &dbCheck{
+				query:      check.DbQueryTmpl,
+				mongoQuery: check.MongoQueryTmpl,
+				response:   check.DbResponseTmpl,
+			})
 		}
 		test.DbChecks = dbChecks
 
+		if testDefinition.PollUntil != nil {
+			test.PollResponses = testDefinition.PollUntil.ResponseTmpls
+			for _, check := range testDefinition.PollUntil.DatabaseChecks {
+				test.PollDbChecks = append(test.PollDbChecks, &dbCheck{
+					query:      check.DbQueryTmpl,
+					mongoQuery: check.MongoQueryTmpl,
+					response:   check.DbResponseTmpl,
+				})
+			}
+		}
+
 		return append(tests, test), nil
 	}
 
@@ -129,6 +242,34 @@ func makeTestFromDefinition(filePath string, testDefinition TestDefinition) ([]T
 			return nil, err
 		}
 
+		if testDefinition.GrpcRequest != nil {
+			grpcRequest := *testDefinition.GrpcRequest
+			grpcRequest.Message, err = substituteArgs(grpcRequest.Message, testCase.RequestArgs)
+			if err != nil {
+				return nil, err
+			}
+			grpcRequest.Metadata, err = substituteArgsToMap(grpcRequest.Metadata, testCase.RequestArgs)
+			if err != nil {
+				return nil, err
+			}
+			test.GrpcRequest = &grpcRequest
+		}
+
+		if testDefinition.Script != nil {
+			test.Script, err = substituteArgsToScript(*testDefinition.Script, testCase.ScriptArgs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if testDefinition.StderrTmpl != nil {
+			stderr, err := substituteArgs(*testDefinition.StderrTmpl, testCase.ScriptArgs)
+			if err != nil {
+				return nil, err
+			}
+			test.StderrTmpl = &stderr
+		}
+
 		// substitute ResponseArgs to different parts of response
 		test.Responses = make(map[int]string)
 		for status, tpl := range testDefinition.ResponseTmpls {
@@ -201,7 +342,12 @@ func makeTestFromDefinition(filePath string, testDefinition TestDefinition) ([]T
 				return nil, err
 			}
 
-			c := &dbCheck{query: query}
+			mongoQuery, err := substituteArgsToMongoQuery(check.MongoQueryTmpl, testCase.DbQueryArgs)
+			if err != nil {
+				return nil, err
+			}
+
+			c := &dbCheck{query: query, mongoQuery: mongoQuery}
 			for _, tpl := range check.DbResponseTmpl {
 				responseString, err := substituteArgs(tpl, testCase.DbResponseArgs)
 				if err != nil {
@@ -216,8 +362,125 @@ func makeTestFromDefinition(filePath string, testDefinition TestDefinition) ([]T
 
 		test.DbChecks = dbChecks
 
+		if testDefinition.PollUntil != nil {
+			test.PollResponses, test.PollDbChecks, err = makePollCondition(testDefinition.PollUntil, testCase)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		tests = append(tests, test)
 	}
 
 	return tests, nil
 }
+
+// Make a test which executes the steps from the given test definition one by one.
+func makeScenarioFromDefinition(filePath string, testDefinition TestDefinition) ([]Test, error) {
+	if len(testDefinition.Cases) != 0 {
+		return nil, fmt.Errorf("test %s: cases are not supported for tests with steps", testDefinition.Name)
+	}
+	if testDefinition.Method != "" || testDefinition.RequestTmpl != "" {
+		return nil, fmt.Errorf("test %s: request must be defined in steps", testDefinition.Name)
+	}
+	if keys := stepKeys(testDefinition); len(keys) != 0 {
+		return nil, fmt.Errorf("test %s: %s must be defined in steps", testDefinition.Name, strings.Join(keys, ", "))
+	}
+
+	test := Test{TestDefinition: testDefinition, Filename: filePath}
+	test.BeforeScript = testDefinition.BeforeScriptParams.PathTmpl
+	test.AfterRequestScript = testDefinition.AfterRequestScriptParams.PathTmpl
+
+	for i, stepDefinition := range testDefinition.StepDefinitions {
+		if len(stepDefinition.StepDefinitions) != 0 {
+			return nil, fmt.Errorf("test %s: steps can't be nested", testDefinition.Name)
+		}
+		if len(stepDefinition.Cases) != 0 {
+			return nil, fmt.Errorf("test %s: cases are not supported for steps", testDefinition.Name)
+		}
+		if stepDefinition.Name == "" {
+			stepDefinition.Name = fmt.Sprintf("step #%d", i+1)
+		}
+
+		steps, err := makeTestFromDefinition(filePath, stepDefinition)
+		if err != nil {
+			return nil, err
+		}
+		test.Steps = append(test.Steps, &steps[0])
+	}
+
+	return []Test{test}, nil
+}
+
+// stepKeys returns the keys of the scenario which are applied to a single request,
+// they are ignored by the scenario, so they must be defined in its steps
+func stepKeys(d TestDefinition) []string {
+	var keys []string
+	add := func(isSet bool, key string) {
+		if isSet {
+			keys = append(keys, key)
+		}
+	}
+	add(d.RequestURL != "", "path")
+	add(d.QueryParams != "", "query")
+	add(d.HeadersVal != nil, "headers")
+	add(d.CookiesVal != nil, "cookies")
+	add(d.Form != nil, "form")
+	add(d.GrpcRequest != nil, "grpc")
+	add(d.Script != nil, "script")
+	add(d.StderrTmpl != nil, "stderr")
+	add(d.ResponseTmpls != nil, "response")
+	add(d.ResponseHeaders != nil, "responseHeaders")
+	add(d.VariablesToSet != nil, "variables_to_set")
+	add(d.DbQueryTmpl != "", "dbQuery")
+	add(d.DbResponseTmpl != nil, "dbResponse")
+	add(d.DatabaseChecks != nil, "dbChecks")
+	add(d.BrokerChecks != nil, "brokerChecks")
+	add(d.RequestTimeoutValue != 0, "timeout")
+	add(d.MaxResponseTimeValue != 0, "maxResponseTime")
+	add(d.RetryParams.Attempts != 0, "retry")
+	add(d.PollUntil != nil, "pollUntil")
+	return keys
+}
+
+// makePollCondition substitutes case arguments to the poll condition
+// the same way as to the response and DB checks of the test
+func makePollCondition(condition *PollCondition, testCase CaseData) (map[int]string, []models.DatabaseCheck, error) {
+	var err error
+
+	responses := make(map[int]string)
+	for status, tpl := range condition.ResponseTmpls {
+		args, ok := testCase.ResponseArgs[status]
+		if !ok {
+			responses[status] = tpl
+			continue
+		}
+		responses[status], err = substituteArgs(tpl, args)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var dbChecks []models.DatabaseCheck
+	for _, check := range condition.DatabaseChecks {
+		query, err := substituteArgs(check.DbQueryTmpl, testCase.DbQueryArgs)
+		if err != nil {
+			return nil, nil, err
+		}
+		mongoQuery, err := substituteArgsToMongoQuery(check.MongoQueryTmpl, testCase.DbQueryArgs)
+		if err != nil {
+			return nil, nil, err
+		}
+		c := &dbCheck{query: query, mongoQuery: mongoQuery}
+		for _, tpl := range check.DbResponseTmpl {
+			responseString, err := substituteArgs(tpl, testCase.DbResponseArgs)
+			if err != nil {
+				return nil, nil, err
+			}
+			c.response = append(c.response, responseString)
+		}
+		dbChecks = append(dbChecks, c)
+	}
+
+	return responses, dbChecks, nil
+}
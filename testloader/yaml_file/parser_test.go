@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +72,85 @@ func TestParseTestsWithCases(t *testing.T) {
 		t.Errorf("wait len(tests) == 2, got len(tests) == %d", len(tests))
 	}
 }
+
+func TestParsePollUntilWithoutRetry(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "gonkey-poll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := fmt.Fprint(tmpfile, `
+- name: poll
+  method: GET
+  path: /orders/1
+  pollUntil:
+    response:
+      200: '{"status": "processed"}'
+  response:
+    200: '{"status": "processed"}'
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parseTestDefinitionFile(tmpfile.Name())
+	if err == nil || !strings.Contains(err.Error(), "pollUntil requires retry") {
+		t.Errorf("wait error about missing retry, got %v", err)
+	}
+}
+
+func TestParseInvalidRetryOn(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "gonkey-retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := fmt.Fprint(tmpfile, `
+- name: retry
+  method: GET
+  path: /orders/1
+  retry:
+    attempts: 3
+    retryOn: [5xx, unavailable]
+  response:
+    200: '{"status": "processed"}'
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parseTestDefinitionFile(tmpfile.Name())
+	if err == nil || !strings.Contains(err.Error(), "retryOn") {
+		t.Errorf("wait error about invalid retryOn, got %v", err)
+	}
+}
+
+func TestParseScenarioWithChecksOutsideSteps(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "gonkey-scenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := fmt.Fprint(tmpfile, `
+- name: scenario
+  steps:
+    - method: GET
+      path: /orders/1
+      response:
+        200: '{}'
+  response:
+    200: '{"status": "processed"}'
+  dbChecks:
+    - dbQuery: SELECT status FROM orders
+      dbResponse:
+        - '{"status": "processed"}'
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parseTestDefinitionFile(tmpfile.Name())
+	if err == nil || !strings.Contains(err.Error(), "response, dbChecks must be defined in steps") {
+		t.Errorf("wait error about checks outside steps, got %v", err)
+	}
+}
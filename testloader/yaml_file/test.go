@@ -7,19 +7,24 @@ import (
 )
 
 type dbCheck struct {
-	query    string
-	response []string
+	query      string
+	mongoQuery *models.MongoQuery
+	response   []string
 }
 
-func (c *dbCheck) DbQueryString() string        { return c.query }
-func (c *dbCheck) DbResponseJson() []string     { return c.response }
-func (c *dbCheck) SetDbQueryString(q string)    { c.query = q }
-func (c *dbCheck) SetDbResponseJson(r []string) { c.response = r }
+func (c *dbCheck) DbQueryString() string              { return c.query }
+func (c *dbCheck) DbResponseJson() []string           { return c.response }
+func (c *dbCheck) GetMongoQuery() *models.MongoQuery  { return c.mongoQuery }
+func (c *dbCheck) SetDbQueryString(q string)          { c.query = q }
+func (c *dbCheck) SetDbResponseJson(r []string)       { c.response = r }
+func (c *dbCheck) SetMongoQuery(q *models.MongoQuery) { c.mongoQuery = q }
 
 type Test struct {
 	TestDefinition
 
 	Filename string
+	// FileHooks are shared by all tests of the file
+	FileHooks *models.Hooks
 
 	Request            string
 	Responses          map[int]string
@@ -30,6 +35,11 @@ type Test struct {
 	DbResponse         []string
 
 	DbChecks []models.DatabaseCheck
+
+	PollResponses map[int]string
+	PollDbChecks  []models.DatabaseCheck
+
+	Steps []models.TestInterface
 }
 
 func (t *Test) ToQuery() string {
@@ -94,6 +104,10 @@ func (t *Test) Fixtures() []string {
 	return t.FixtureFiles
 }
 
+func (t *Test) BrokerFixtures() []string {
+	return t.BrokerFixtureFiles
+}
+
 func (t *Test) ServiceMocks() map[string]interface{} {
 	return t.MocksDefinition
 }
@@ -102,6 +116,42 @@ func (t *Test) Pause() int {
 	return t.PauseValue
 }
 
+func (t *Test) IsolationGroup() string {
+	return t.IsolationGroupName
+}
+
+func (t *Test) Parallel() bool {
+	return t.ParallelValue == nil || *t.ParallelValue
+}
+
+func (t *Test) RequestTimeout() int {
+	return t.RequestTimeoutValue
+}
+
+func (t *Test) MaxResponseTime() int {
+	return t.MaxResponseTimeValue
+}
+
+func (t *Test) GetRetryParams() models.RetryParams {
+	return t.RetryParams
+}
+
+func (t *Test) GetPollCondition() models.TestInterface {
+	if t.PollUntil == nil {
+		return nil
+	}
+
+	res := *t
+	if len(t.PollResponses) != 0 {
+		res.Responses = t.PollResponses
+	}
+	res.DbQuery = ""
+	res.DbResponse = nil
+	res.DbChecks = t.PollDbChecks
+	res.PollUntil = nil
+	return &res
+}
+
 func (t *Test) BeforeScriptPath() string {
 	return t.BeforeScript
 }
@@ -143,6 +193,13 @@ func (t *Test) DbResponseJson() []string {
 func (t *Test) GetDatabaseChecks() []models.DatabaseCheck       { return t.DbChecks }
 func (t *Test) SetDatabaseChecks(checks []models.DatabaseCheck) { t.DbChecks = checks }
 
+func (t *Test) GetBrokerChecks() []models.BrokerCheck       { return t.BrokerChecks }
+func (t *Test) SetBrokerChecks(checks []models.BrokerCheck) { t.BrokerChecks = checks }
+
+func (t *Test) GetMockCallChecks() []models.MockCallCheck       { return t.MockCalls }
+func (t *Test) SetMockCallChecks(checks []models.MockCallCheck) { t.MockCalls = checks }
+func (t *Test) MockCallsInOrder() bool                          { return t.MockCallsInOrderValue }
+
 func (t *Test) GetVariables() map[string]string {
 	return t.Variables
 }
@@ -151,20 +208,105 @@ func (t *Test) GetForm() *models.Form {
 	return t.Form
 }
 
+func (t *Test) GetGrpcRequest() *models.GrpcRequest {
+	return t.GrpcRequest
+}
+
+func (t *Test) GetScript() *models.ScriptRequest {
+	return t.Script
+}
+
+func (t *Test) GetExpectedStderr() (string, bool) {
+	if t.StderrTmpl == nil {
+		return "", false
+	}
+	return *t.StderrTmpl, true
+}
+
 func (t *Test) GetVariablesToSet() map[int]map[string]string {
 	return t.VariablesToSet
 }
 
+func (t *Test) GetSteps() []models.TestInterface {
+	return t.Steps
+}
+
 func (t *Test) GetFileName() string {
 	return t.Filename
 }
 
+func (t *Test) GetFileHooks() *models.Hooks {
+	return t.FileHooks
+}
+
+func (t *Test) GetAfterTestHooks() []models.Hook {
+	return t.AfterTestHooks
+}
+
+// Clone returns a copy of the test, the parts changed in place by variables substitution
+// (database checks, mocks and steps) are copied deeply
 func (t *Test) Clone() models.TestInterface {
 	res := *t
 
+	res.DbChecks = cloneDbChecks(t.DbChecks)
+	res.PollDbChecks = cloneDbChecks(t.PollDbChecks)
+	if t.MocksDefinition != nil {
+		res.MocksDefinition = make(map[string]interface{}, len(t.MocksDefinition))
+		for name, definition := range t.MocksDefinition {
+			res.MocksDefinition[name] = cloneValue(definition)
+		}
+	}
+	if t.Steps != nil {
+		res.Steps = make([]models.TestInterface, len(t.Steps))
+		for i, step := range t.Steps {
+			res.Steps[i] = step.Clone()
+		}
+	}
+
 	return &res
 }
 
+func cloneDbChecks(checks []models.DatabaseCheck) []models.DatabaseCheck {
+	if checks == nil {
+		return nil
+	}
+	res := make([]models.DatabaseCheck, len(checks))
+	for i, check := range checks {
+		if c, ok := check.(*dbCheck); ok {
+			copied := *c
+			check = &copied
+		}
+		res[i] = check
+	}
+	return res
+}
+
+// cloneValue copies maps and slices of the value parsed from YAML
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			res[key] = cloneValue(item)
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			res[key] = cloneValue(item)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = cloneValue(item)
+		}
+		return res
+	default:
+		return value
+	}
+}
+
 func (t *Test) SetQuery(val string) {
 	var query strings.Builder
 	query.Grow(len(val) + 1)
@@ -190,6 +332,18 @@ func (t *Test) SetForm(val *models.Form) {
 	t.Form = val
 }
 
+func (t *Test) SetGrpcRequest(val *models.GrpcRequest) {
+	t.GrpcRequest = val
+}
+
+func (t *Test) SetScript(val *models.ScriptRequest) {
+	t.Script = val
+}
+
+func (t *Test) SetExpectedStderr(val string) {
+	t.StderrTmpl = &val
+}
+
 func (t *Test) SetResponses(val map[int]string) {
 	t.Responses = val
 }
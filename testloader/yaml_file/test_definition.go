@@ -8,15 +8,19 @@ import (
 type TestDefinition struct {
 	Name                     string                    `json:"name" yaml:"name"`
 	Status                   string                    `json:"status" yaml:"status"`
+	Tags                     []string                  `json:"tags" yaml:"tags"`
 	Variables                map[string]string         `json:"variables" yaml:"variables"`
 	VariablesToSet           VariablesToSet            `json:"variables_to_set" yaml:"variables_to_set"`
 	Form                     *models.Form              `json:"form" yaml:"form"`
+	GrpcRequest              *models.GrpcRequest       `json:"grpc" yaml:"grpc"`
+	Script                   *models.ScriptRequest     `json:"script" yaml:"script"`
 	Method                   string                    `json:"method" yaml:"method"`
 	RequestURL               string                    `json:"path" yaml:"path"`
 	QueryParams              string                    `json:"query" yaml:"query"`
 	RequestTmpl              string                    `json:"request" yaml:"request"`
 	ResponseTmpls            map[int]string            `json:"response" yaml:"response"`
 	ResponseHeaders          map[int]map[string]string `json:"responseHeaders" yaml:"responseHeaders"`
+	StderrTmpl               *string                   `json:"stderr" yaml:"stderr"`
 	BeforeScriptParams       scriptParams              `json:"beforeScript" yaml:"beforeScript"`
 	AfterRequestScriptParams scriptParams              `json:"afterRequestScript" yaml:"afterRequestScript"`
 	HeadersVal               map[string]string         `json:"headers" yaml:"headers"`
@@ -24,11 +28,23 @@ type TestDefinition struct {
 	Cases                    []CaseData                `json:"cases" yaml:"cases"`
 	ComparisonParams         compare.CompareParams     `json:"comparisonParams" yaml:"comparisonParams"`
 	FixtureFiles             []string                  `json:"fixtures" yaml:"fixtures"`
+	BrokerFixtureFiles       []string                  `json:"brokerFixtures" yaml:"brokerFixtures"`
 	MocksDefinition          map[string]interface{}    `json:"mocks" yaml:"mocks"`
 	PauseValue               int                       `json:"pause" yaml:"pause"`
 	DbQueryTmpl              string                    `json:"dbQuery" yaml:"dbQuery"`
 	DbResponseTmpl           []string                  `json:"dbResponse" yaml:"dbResponse"`
 	DatabaseChecks           []DatabaseCheck           `json:"dbChecks" yaml:"dbChecks"`
+	BrokerChecks             []models.BrokerCheck      `json:"brokerChecks" yaml:"brokerChecks"`
+	MockCalls                []models.MockCallCheck    `json:"mockCalls" yaml:"mockCalls"`
+	MockCallsInOrderValue    bool                      `json:"mockCallsInOrder" yaml:"mockCallsInOrder"`
+	IsolationGroupName       string                    `json:"isolationGroup" yaml:"isolationGroup"`
+	ParallelValue            *bool                     `json:"parallel" yaml:"parallel"`
+	RequestTimeoutValue      int                       `json:"timeout" yaml:"timeout"`
+	MaxResponseTimeValue     int                       `json:"maxResponseTime" yaml:"maxResponseTime"`
+	RetryParams              models.RetryParams        `json:"retry" yaml:"retry"`
+	PollUntil                *PollCondition            `json:"pollUntil" yaml:"pollUntil"`
+	StepDefinitions          []TestDefinition          `json:"steps" yaml:"steps"`
+	AfterTestHooks           []models.Hook             `json:"afterTest" yaml:"afterTest"`
 }
 
 type CaseData struct {
@@ -36,14 +52,21 @@ type CaseData struct {
 	ResponseArgs           map[int]map[string]interface{} `json:"responseArgs" yaml:"responseArgs"`
 	BeforeScriptArgs       map[string]interface{}         `json:"beforeScriptArgs" yaml:"beforeScriptArgs"`
 	AfterRequestScriptArgs map[string]interface{}         `json:"afterRequestScriptArgs" yaml:"afterRequestScriptArgs"`
+	ScriptArgs             map[string]interface{}         `json:"scriptArgs" yaml:"scriptArgs"`
 	DbQueryArgs            map[string]interface{}         `json:"dbQueryArgs" yaml:"dbQueryArgs"`
 	DbResponseArgs         map[string]interface{}         `json:"dbResponseArgs" yaml:"dbResponseArgs"`
 	DbResponse             []string                       `json:"dbResponse" yaml:"dbResponse"`
 }
 
 type DatabaseCheck struct {
-	DbQueryTmpl    string   `json:"dbQuery" yaml:"dbQuery"`
-	DbResponseTmpl []string `json:"dbResponse" yaml:"dbResponse"`
+	DbQueryTmpl    string             `json:"dbQuery" yaml:"dbQuery"`
+	MongoQueryTmpl *models.MongoQuery `json:"mongoQuery" yaml:"mongoQuery"`
+	DbResponseTmpl []string           `json:"dbResponse" yaml:"dbResponse"`
+}
+
+type PollCondition struct {
+	ResponseTmpls  map[int]string  `json:"response" yaml:"response"`
+	DatabaseChecks []DatabaseCheck `json:"dbChecks" yaml:"dbChecks"`
 }
 
 type scriptParams struct {
@@ -3,6 +3,8 @@ package yaml_file
 import (
 	"reflect"
 	"testing"
+
+	"github.com/lamoda/gonkey/models"
 )
 
 func TestNewTestWithCases(t *testing.T) {
@@ -81,3 +83,38 @@ func TestNewTestWithCases(t *testing.T) {
 		t.Errorf("want filename %s, got %s", "cases/example.yaml", filename)
 	}
 }
+
+func TestCloneShouldCopyPartsChangedByVariables(t *testing.T) {
+	step := &Test{DbChecks: []models.DatabaseCheck{&dbCheck{query: "SELECT {{ $id }}"}}}
+	test := &Test{
+		DbChecks: []models.DatabaseCheck{&dbCheck{query: "SELECT {{ $id }}"}},
+		Steps:    []models.TestInterface{step},
+	}
+	test.MocksDefinition = map[string]interface{}{
+		"backend": map[interface{}]interface{}{
+			"body":    "{{ $id }}",
+			"headers": []interface{}{"{{ $id }}"},
+		},
+	}
+
+	clone := test.Clone().(*Test)
+	clone.DbChecks[0].SetDbQueryString("SELECT 1")
+	clone.Steps[0].GetDatabaseChecks()[0].SetDbQueryString("SELECT 1")
+	mock := clone.MocksDefinition["backend"].(map[interface{}]interface{})
+	mock["body"] = "1"
+	mock["headers"].([]interface{})[0] = "1"
+
+	expected := &Test{
+		DbChecks: []models.DatabaseCheck{&dbCheck{query: "SELECT {{ $id }}"}},
+		Steps:    []models.TestInterface{&Test{DbChecks: []models.DatabaseCheck{&dbCheck{query: "SELECT {{ $id }}"}}}},
+	}
+	expected.MocksDefinition = map[string]interface{}{
+		"backend": map[interface{}]interface{}{
+			"body":    "{{ $id }}",
+			"headers": []interface{}{"{{ $id }}"},
+		},
+	}
+	if !reflect.DeepEqual(test, expected) {
+		t.Errorf("original test is changed: %+v", test)
+	}
+}
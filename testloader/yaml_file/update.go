@@ -0,0 +1,462 @@
+package yaml_file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+// Updater rewrites the expected response bodies and DB responses of the failed tests
+// in their files with the actual ones. Matchers `$matchRegexp(...)` and template placeholders
+// of the expected values are kept, the rest of the file is left as is.
+//
+// Updater is used as the output of the runner, the files are rewritten by Save after the run.
+type Updater struct {
+	mu      sync.Mutex
+	results map[string][]*models.Result
+	skipped []string
+}
+
+func NewUpdater() *Updater {
+	return &Updater{results: make(map[string][]*models.Result)}
+}
+
+func (u *Updater) Process(t models.TestInterface, result *models.Result) error {
+	if len(result.Errors) == 0 || t.GetStatus() == "skipped" || t.GetStatus() == "broken" {
+		return nil
+	}
+	test, ok := t.(*Test)
+	if !ok {
+		return nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(test.Cases) != 0 {
+		u.skip(test.Name, test.Filename, "tests with cases are not updated")
+		return nil
+	}
+	u.results[test.Filename] = append(u.results[test.Filename], result)
+	return nil
+}
+
+func (u *Updater) skip(name, file, reason string) {
+	u.skipped = append(u.skipped, fmt.Sprintf("%s (%s): %s", name, file, reason))
+}
+
+// Save rewrites the files, it returns names of the updated tests
+// and the reasons the other failed tests were not updated
+func (u *Updater) Save() (updated, skipped []string, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	files := make([]string, 0, len(u.results))
+	for file := range u.results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		fileUpdated, err := u.updateFile(file, u.results[file])
+		if err != nil {
+			return nil, nil, err
+		}
+		updated = append(updated, fileUpdated...)
+	}
+	return updated, u.skipped, nil
+}
+
+func (u *Updater) updateFile(file string, results []*models.Result) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	testNodes := fileTestNodes(&doc)
+
+	src := newSource(data)
+	var updated []string
+	for _, result := range results {
+		name := result.Test.GetName()
+		var found []*yaml.Node
+		for _, n := range testNodes {
+			if nameNode := mappingValue(n, "name"); nameNode != nil && nameNode.Value == name {
+				found = append(found, n)
+			}
+		}
+		if len(found) != 1 {
+			u.skip(name, file, "the test is not found or its name is not unique in the file")
+			continue
+		}
+
+		edits := len(src.edits)
+		if reason := src.updateTest(found[0], result); reason != "" {
+			u.skip(name, file, reason)
+			continue
+		}
+		if len(src.edits) != edits {
+			updated = append(updated, name)
+		}
+	}
+
+	if len(updated) == 0 {
+		return nil, nil
+	}
+	return updated, ioutil.WriteFile(file, src.apply(), 0644)
+}
+
+// fileTestNodes returns the nodes of the tests of the file, which is either the list of the tests
+// or the mapping with `tests` key
+func fileTestNodes(doc *yaml.Node) []*yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.MappingNode {
+		root = mappingValue(root, "tests")
+	}
+	if root == nil || root.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return root.Content
+}
+
+// mappingValue returns the value of the key of the mapping, nil if there is no such key
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingKey returns the key node of the mapping value
+func mappingKey(n *yaml.Node, value *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i+1] == value {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// source is the content of the test file with the pending replacements
+type source struct {
+	data       []byte
+	lineStarts []int
+	edits      []edit
+}
+
+func newSource(data []byte) *source {
+	s := &source{data: data, lineStarts: []int{0}}
+	for i, c := range data {
+		if c == '\n' {
+			s.lineStarts = append(s.lineStarts, i+1)
+		}
+	}
+	return s
+}
+
+// offset returns the position of the node in the data
+func (s *source) offset(n *yaml.Node) int {
+	return s.lineStarts[n.Line-1] + n.Column - 1
+}
+
+func (s *source) lineEnd(offset int) int {
+	if i := bytes.IndexByte(s.data[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(s.data)
+}
+
+func (s *source) lineIndent(offset int) string {
+	start := bytes.LastIndexByte(s.data[:offset], '\n') + 1
+	line := s.data[start:s.lineEnd(start)]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+func (s *source) apply() []byte {
+	sort.Slice(s.edits, func(i, j int) bool { return s.edits[i].start > s.edits[j].start })
+	data := s.data
+	for _, e := range s.edits {
+		res := make([]byte, 0, len(data)-(e.end-e.start)+len(e.text))
+		res = append(res, data[:e.start]...)
+		res = append(res, e.text...)
+		res = append(res, data[e.end:]...)
+		data = res
+	}
+	return data
+}
+
+// updateTest adds the edits of the test node, the reason is returned if the test can't be updated
+func (s *source) updateTest(n *yaml.Node, result *models.Result) string {
+	if !result.Test.NeedsCheckingValues() {
+		return "values are not checked by the test"
+	}
+
+	if steps := mappingValue(n, "steps"); steps != nil {
+		for i, stepResult := range result.Steps {
+			if len(stepResult.Errors) == 0 {
+				continue
+			}
+			if i >= len(steps.Content) {
+				break
+			}
+			if reason := s.updateTest(steps.Content[i], stepResult); reason != "" {
+				return fmt.Sprintf("step %s: %s", stepResult.Test.GetName(), reason)
+			}
+		}
+		return ""
+	}
+
+	if responses := mappingValue(n, "response"); responses != nil {
+		body := mappingValue(responses, strconv.Itoa(result.ResponseStatusCode))
+		if body == nil {
+			return fmt.Sprintf("response for status %d is not defined", result.ResponseStatusCode)
+		}
+		if body.Kind == yaml.ScalarNode {
+			newBody, changed, err := updatedBody(body.Value, result.ResponseBody, result.Test.DisallowExtraFields())
+			if err != nil {
+				return err.Error()
+			}
+			if changed {
+				if reason := s.replaceScalar(responses, body, newBody); reason != "" {
+					return reason
+				}
+			}
+		}
+	}
+
+	// the DB results are matched to the checks by the queries
+	used := make([]bool, len(result.DatabaseResult))
+	dbResult := func(query string) *models.DatabaseResult {
+		for i := range result.DatabaseResult {
+			if !used[i] && result.DatabaseResult[i].Query == query {
+				used[i] = true
+				return &result.DatabaseResult[i]
+			}
+		}
+		return nil
+	}
+
+	if mappingValue(n, "dbQuery") != nil {
+		if r := dbResult(result.Test.DbQueryString()); r != nil {
+			if reason := s.updateDbResponse(mappingValue(n, "dbResponse"), r.Response); reason != "" {
+				return reason
+			}
+		}
+	}
+	if checks := mappingValue(n, "dbChecks"); checks != nil {
+		testChecks := result.Test.GetDatabaseChecks()
+		for i, check := range checks.Content {
+			if i >= len(testChecks) || mappingValue(check, "dbQuery") == nil {
+				continue
+			}
+			if r := dbResult(testChecks[i].DbQueryString()); r != nil {
+				if reason := s.updateDbResponse(mappingValue(check, "dbResponse"), r.Response); reason != "" {
+					return reason
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (s *source) updateDbResponse(rows *yaml.Node, actual []string) string {
+	if rows == nil || rows.Kind != yaml.SequenceNode {
+		return ""
+	}
+	if len(rows.Content) == len(actual) {
+		for i, row := range rows.Content {
+			if row.Kind != yaml.ScalarNode {
+				return "dbResponse rows should be strings"
+			}
+			newRow, changed, err := updatedBody(row.Value, actual[i], false)
+			if err != nil {
+				return err.Error()
+			}
+			if changed {
+				if reason := s.replaceScalar(rows, row, newRow); reason != "" {
+					return reason
+				}
+			}
+		}
+		return ""
+	}
+
+	// the number of rows is changed, the whole list is rewritten
+	if len(rows.Content) == 0 || len(actual) == 0 || rows.Style&yaml.FlowStyle != 0 {
+		return "the number of dbResponse rows is changed and the list can't be rewritten"
+	}
+	first, last := rows.Content[0], rows.Content[len(rows.Content)-1]
+	start := s.offset(first)
+	indent := s.lineIndent(start)
+	start = bytes.LastIndexByte(s.data[:start], '\n') + 1
+	_, end, ok := s.scalarExtent(last)
+	if !ok {
+		return "dbResponse rows should be strings"
+	}
+
+	lines := make([]string, len(actual))
+	for i, row := range actual {
+		expected := "{}"
+		if i < len(rows.Content) {
+			expected = rows.Content[i].Value
+		}
+		if newRow, changed, err := updatedBody(expected, row, i >= len(rows.Content)); err == nil {
+			if changed {
+				row = newRow
+			} else {
+				row = expected
+			}
+		}
+		// the rows are written on a single line each
+		if decoded, err := decodeOrderedJSON(row); err == nil {
+			row = renderJSON(decoded, "")
+		}
+		lines[i] = indent + "- " + singleQuoted(row)
+	}
+	s.edits = append(s.edits, edit{start: start, end: end, text: strings.Join(lines, "\n")})
+	return ""
+}
+
+// scalarExtent returns the positions of the scalar in the data and the indentation of the block scalar content
+func (s *source) scalarExtent(n *yaml.Node) (start, end int, ok bool) {
+	start = s.offset(n)
+	switch {
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		end = s.lineEnd(start)
+		contentIndent := -1
+		for pos := end + 1; pos < len(s.data); {
+			lineEnd := s.lineEnd(pos)
+			line := s.data[pos:lineEnd]
+			trimmed := bytes.TrimLeft(line, " \t")
+			if len(trimmed) != 0 {
+				indent := len(line) - len(trimmed)
+				if contentIndent < 0 {
+					contentIndent = indent
+				}
+				if indent < contentIndent || contentIndent <= s.column(start) {
+					break
+				}
+				end = lineEnd
+			}
+			pos = lineEnd + 1
+		}
+		return start, end, true
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(s.data); i++ {
+			if s.data[i] == '\'' {
+				if i+1 < len(s.data) && s.data[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start, i + 1, true
+			}
+		}
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(s.data); i++ {
+			switch s.data[i] {
+			case '\\':
+				i++
+			case '"':
+				return start, i + 1, true
+			}
+		}
+	case n.Style&yaml.FlowStyle == 0 && !strings.Contains(n.Value, "\n"):
+		end = start + len(n.Value)
+		if end > len(s.data) || string(s.data[start:end]) != n.Value {
+			return 0, 0, false
+		}
+		return start, end, true
+	}
+	return 0, 0, false
+}
+
+// column returns the indentation of the line the block scalar header is on
+func (s *source) column(offset int) int {
+	return len(s.lineIndent(offset))
+}
+
+// replaceScalar replaces the value of the scalar keeping its style when possible
+func (s *source) replaceScalar(parent, n *yaml.Node, value string) string {
+	start, end, ok := s.scalarExtent(n)
+	if !ok {
+		return "the expected value can't be rewritten"
+	}
+
+	var text string
+	switch {
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		header := string(s.data[start:s.lineEnd(start)])
+		if i := strings.Index(header, " #"); i >= 0 {
+			header = header[:i]
+		}
+		header = strings.Replace(strings.TrimSpace(header), ">", "|", 1)
+		indent := s.lineIndent(start) + "  "
+		for pos := s.lineEnd(start) + 1; pos < end; pos = s.lineEnd(pos) + 1 {
+			if line := s.data[pos:s.lineEnd(pos)]; len(bytes.TrimSpace(line)) != 0 {
+				indent = s.lineIndent(pos)
+				break
+			}
+		}
+		text = blockScalar(header, indent, value)
+	case strings.Contains(value, "\n"):
+		indent := s.lineIndent(start) + "  "
+		if key := mappingKey(parent, n); key != nil {
+			indent = s.lineIndent(s.offset(key)) + "  "
+		}
+		text = blockScalar("|-", indent, value)
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		text = doubleQuoted(value)
+	default:
+		text = singleQuoted(value)
+	}
+
+	s.edits = append(s.edits, edit{start: start, end: end, text: text})
+	return ""
+}
+
+func blockScalar(header, indent, value string) string {
+	lines := strings.Split(strings.TrimSuffix(value, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
+
+func singleQuoted(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func doubleQuoted(value string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,239 @@
+package yaml_file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// jsonObject is a decoded JSON object which keeps the order of the keys,
+// so the rewritten expected bodies differ from the original ones only in the changed values
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// decodeOrderedJSON decodes the document into *jsonObject, []interface{}, string, json.Number, bool or nil
+func decodeOrderedJSON(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	v, err := decodeOrderedValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err == nil {
+		return nil, errors.New("extra data after JSON document")
+	}
+	return v, nil
+}
+
+func decodeOrderedValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		obj := &jsonObject{values: make(map[string]interface{})}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyToken.(string)
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.values[key]; !ok {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := decoder.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := decoder.Token()
+		return arr, err
+	default:
+		return token, nil
+	}
+}
+
+// isPreservedLeaf returns true for the expected values which are not replaced with the actual ones
+func isPreservedLeaf(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && (strings.HasPrefix(s, "$matchRegexp(") || strings.Contains(s, "{{"))
+}
+
+// mergeJSON returns the actual value keeping matchers and placeholders of the expected one.
+// Fields missing in the expected objects are added only if extra fields are disallowed,
+// as otherwise they are left out of the expectations on purpose.
+func mergeJSON(expected, actual interface{}, addFields bool) interface{} {
+	if isPreservedLeaf(expected) {
+		return expected
+	}
+
+	switch e := expected.(type) {
+	case *jsonObject:
+		a, ok := actual.(*jsonObject)
+		if !ok {
+			return actual
+		}
+		res := &jsonObject{values: make(map[string]interface{})}
+		for _, key := range e.keys {
+			if actualValue, ok := a.values[key]; ok {
+				res.keys = append(res.keys, key)
+				res.values[key] = mergeJSON(e.values[key], actualValue, addFields)
+			}
+		}
+		if addFields {
+			for _, key := range a.keys {
+				if _, ok := e.values[key]; !ok {
+					res.keys = append(res.keys, key)
+					res.values[key] = a.values[key]
+				}
+			}
+		}
+		return res
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return actual
+		}
+		res := make([]interface{}, len(a))
+		for i := range a {
+			if i < len(e) {
+				res[i] = mergeJSON(e[i], a[i], addFields)
+			} else {
+				res[i] = a[i]
+			}
+		}
+		return res
+	default:
+		return actual
+	}
+}
+
+// updatedBody returns the expected body with the values replaced by the actual ones,
+// false if the body is not changed
+func updatedBody(expected, actual string, addFields bool) (string, bool, error) {
+	expectedValue, err := decodeOrderedJSON(expected)
+	if err != nil {
+		if strings.Contains(expected, "{{") || strings.Contains(expected, "$matchRegexp(") {
+			return "", false, errors.New("expected body is a template")
+		}
+		return actual, expected != actual, nil
+	}
+	actualValue, err := decodeOrderedJSON(actual)
+	if err != nil {
+		return actual, true, nil
+	}
+
+	merged := mergeJSON(expectedValue, actualValue, addFields)
+	if reflect.DeepEqual(merged, expectedValue) {
+		return "", false, nil
+	}
+	return renderJSON(merged, jsonIndent(expected)), true, nil
+}
+
+// jsonIndent returns the indentation used in the document, empty for the single-line one
+func jsonIndent(doc string) string {
+	lines := strings.Split(strings.TrimSpace(doc), "\n")
+	if len(lines) < 2 {
+		return ""
+	}
+	for _, line := range lines[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" && len(trimmed) != len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// renderJSON writes the value on a single line with spaces after separators
+// or on several lines if the indentation is given
+func renderJSON(v interface{}, indent string) string {
+	var b bytes.Buffer
+	writeJSON(&b, v, indent, "")
+	return b.String()
+}
+
+func writeJSON(b *bytes.Buffer, v interface{}, indent, prefix string) {
+	newline := func(level string) {
+		if indent == "" {
+			return
+		}
+		b.WriteString("\n")
+		b.WriteString(level)
+	}
+	separator := ", "
+	if indent != "" {
+		separator = ","
+	}
+
+	switch value := v.(type) {
+	case *jsonObject:
+		if len(value.keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{")
+		for i, key := range value.keys {
+			if i != 0 {
+				b.WriteString(separator)
+			}
+			newline(prefix + indent)
+			writeJSONString(b, key)
+			b.WriteString(": ")
+			writeJSON(b, value.values[key], indent, prefix+indent)
+		}
+		newline(prefix)
+		b.WriteString("}")
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[")
+		for i, item := range value {
+			if i != 0 {
+				b.WriteString(separator)
+			}
+			newline(prefix + indent)
+			writeJSON(b, item, indent, prefix+indent)
+		}
+		newline(prefix)
+		b.WriteString("]")
+	case string:
+		writeJSONString(b, value)
+	case json.Number:
+		b.WriteString(value.String())
+	case bool:
+		if value {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case nil:
+		b.WriteString("null")
+	}
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	// Encode adds the newline
+	b.Truncate(b.Len() - 1)
+}
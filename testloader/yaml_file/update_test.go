@@ -0,0 +1,168 @@
+package yaml_file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lamoda/gonkey/models"
+)
+
+const updatedTests = `# comments and formatting are kept
+- name: block body
+  method: GET
+  path: /items/1
+  response:
+    200: |
+      {
+          "name": "new",
+          "id": "$matchRegexp(^\\d+$)",
+          "tags": [
+              "a",
+              "b"
+          ]
+      }
+
+- name: quoted body
+  method: GET
+  path: /status
+  response:
+    200: '{"status": "ok", "count": 2}' # the count changes
+    500: '{"status": "error"}'
+
+- name: template body
+  method: GET
+  path: /items/{{ $id }}
+  response:
+    200: '{"id": {{ $id }}}'
+
+- name: plain body
+  method: GET
+  path: /missing
+  response:
+    404: 'nothing''s here'
+
+- name: db
+  method: GET
+  path: /db
+  response:
+    200: ""
+  dbQuery: SELECT id, name FROM items
+  dbResponse:
+    - '{"id": 1, "name": "b"}'
+    - '{"id": 2, "name": "{{ $name }}"}'
+  dbChecks:
+    - dbQuery: SELECT count(*) AS cnt FROM items
+      dbResponse:
+        - '{"cnt": 2}'
+    - dbQuery: SELECT id FROM orders
+      dbResponse:
+        - '{"id": 1}'
+        - '{"id": 2}'
+
+- name: with cases
+  method: GET
+  path: /cases
+  response:
+    200: '{"value": {{ .value }}}'
+  cases:
+    - responseArgs:
+        200:
+          value: 1
+
+- name: unexpected status
+  method: POST
+  path: /items
+  response:
+    200: '{"id": 1}'
+`
+
+func TestUpdater(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonkey-update")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update", "tests.yaml"))
+	require.NoError(t, err)
+	file := filepath.Join(dir, "tests.yaml")
+	require.NoError(t, ioutil.WriteFile(file, data, 0644))
+
+	tests, err := parseTestDefinitionFile(file)
+	require.NoError(t, err)
+	byName := make(map[string]*Test)
+	for i := range tests {
+		byName[tests[i].GetName()] = &tests[i]
+	}
+
+	failed := []error{assert.AnError}
+	results := []*models.Result{
+		{
+			Test:               byName["block body"],
+			ResponseStatusCode: 200,
+			ResponseBody:       `{"id": 10, "name": "new", "tags": ["a", "b"], "extra": true}`,
+			Errors:             failed,
+		},
+		{
+			Test:               byName["quoted body"],
+			ResponseStatusCode: 200,
+			ResponseBody:       `{"count": 2, "status": "ok"}`,
+			Errors:             failed,
+		},
+		{
+			Test:               byName["template body"],
+			ResponseStatusCode: 200,
+			ResponseBody:       `{"id": 2}`,
+			Errors:             failed,
+		},
+		{
+			Test:               byName["plain body"],
+			ResponseStatusCode: 404,
+			ResponseBody:       `nothing's here`,
+			Errors:             failed,
+		},
+		{
+			Test:               byName["db"],
+			ResponseStatusCode: 200,
+			DatabaseResult: []models.DatabaseResult{
+				{Query: "SELECT id, name FROM items", Response: []string{`{"id":1,"name":"b"}`, `{"id":2,"name":"c"}`}},
+				{Query: "SELECT count(*) AS cnt FROM items", Response: []string{`{"cnt":2}`}},
+				{Query: "SELECT id FROM orders", Response: []string{`{"id":1}`, `{"id":2}`}},
+			},
+			Errors: failed,
+		},
+		{
+			Test:               byName["unexpected status"],
+			ResponseStatusCode: 201,
+			ResponseBody:       `{"id": 1}`,
+			Errors:             failed,
+		},
+		{
+			Test:               byName["with cases #0"],
+			ResponseStatusCode: 200,
+			ResponseBody:       `{"value": 2}`,
+			Errors:             failed,
+		},
+	}
+
+	updater := NewUpdater()
+	for _, result := range results {
+		require.NoError(t, updater.Process(result.Test, result))
+	}
+	updated, skipped, err := updater.Save()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"block body", "quoted body", "plain body", "db"}, updated)
+	require.Len(t, skipped, 3)
+	assert.Contains(t, skipped[0], "with cases")
+	assert.Contains(t, skipped[1], "template body")
+	assert.Contains(t, skipped[2], "unexpected status (")
+	assert.Contains(t, skipped[2], "response for status 201 is not defined")
+
+	data, err = ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, updatedTests, string(data))
+}
@@ -3,6 +3,8 @@ package yaml_file
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/lamoda/gonkey/models"
@@ -11,6 +13,10 @@ import (
 type YamlFileLoader struct {
 	testsLocation string
 	fileFilter    string
+	fileGlob      string
+	nameFilter    *regexp.Regexp
+	tags          []string
+	excludeTags   []string
 }
 
 func NewLoader(testsLocation string) *YamlFileLoader {
@@ -27,7 +33,9 @@ func (l *YamlFileLoader) Load() (chan models.TestInterface, error) {
 	ch := make(chan models.TestInterface)
 	go func() {
 		for i := range fileTests {
-			ch <- &fileTests[i]
+			if l.fitsTestFilter(&fileTests[i]) {
+				ch <- &fileTests[i]
+			}
 		}
 		close(ch)
 	}()
@@ -38,6 +46,28 @@ func (l *YamlFileLoader) SetFileFilter(f string) {
 	l.fileFilter = f
 }
 
+// SetFileGlob sets the pattern (in terms of filepath.Match) of the test files to load,
+// it is matched against the path relative to the tests location and against the file name
+func (l *YamlFileLoader) SetFileGlob(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return err
+	}
+	l.fileGlob = pattern
+	return nil
+}
+
+// SetNameFilter sets the regular expression the names of the tests to load must match
+func (l *YamlFileLoader) SetNameFilter(re *regexp.Regexp) {
+	l.nameFilter = re
+}
+
+// SetTagsFilter sets the tags the tests to load must have at least one of,
+// and the tags they must have none of
+func (l *YamlFileLoader) SetTagsFilter(tags, excludeTags []string) {
+	l.tags = tags
+	l.excludeTags = excludeTags
+}
+
 func (l *YamlFileLoader) parseTestsWithCases(path string) ([]Test, error) {
 	stat, err := os.Stat(path)
 	if err != nil {
@@ -73,10 +103,45 @@ func (l *YamlFileLoader) lookupPath(path string, fi os.FileInfo) ([]Test, error)
 }
 
 func (l *YamlFileLoader) fitsFilter(fileName string) bool {
-	if l.fileFilter == "" {
-		return true
+	if l.fileFilter != "" && !strings.Contains(fileName, l.fileFilter) {
+		return false
+	}
+	if l.fileGlob != "" {
+		relName, err := filepath.Rel(l.testsLocation, fileName)
+		if err != nil || relName == "." {
+			relName = fileName
+		}
+		matchedPath, _ := filepath.Match(l.fileGlob, relName)
+		matchedName, _ := filepath.Match(l.fileGlob, filepath.Base(fileName))
+		if !matchedPath && !matchedName {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *YamlFileLoader) fitsTestFilter(t *Test) bool {
+	if l.nameFilter != nil && !l.nameFilter.MatchString(t.GetName()) {
+		return false
+	}
+	if len(l.tags) != 0 && !hasAnyTag(t.Tags, l.tags) {
+		return false
+	}
+	if hasAnyTag(t.Tags, l.excludeTags) {
+		return false
+	}
+	return true
+}
+
+func hasAnyTag(testTags, tags []string) bool {
+	for _, tag := range tags {
+		for _, testTag := range testTags {
+			if tag == testTag {
+				return true
+			}
+		}
 	}
-	return strings.Contains(fileName, l.fileFilter)
+	return false
 }
 
 func isYmlFile(name string) bool {
@@ -0,0 +1,80 @@
+package yaml_file
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadNames(t *testing.T, l *YamlFileLoader) []string {
+	ch, err := l.Load()
+	require.NoError(t, err)
+
+	var names []string
+	for test := range ch {
+		names = append(names, test.GetName())
+	}
+	return names
+}
+
+func TestLoaderFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(l *YamlFileLoader)
+		expected []string
+	}{
+		{
+			name:     "no filters",
+			setup:    func(l *YamlFileLoader) {},
+			expected: []string{"create order", "cancel order", "get user", "list users"},
+		},
+		{
+			name:     "tags",
+			setup:    func(l *YamlFileLoader) { l.SetTagsFilter([]string{"smoke", "slow"}, nil) },
+			expected: []string{"create order", "cancel order", "get user"},
+		},
+		{
+			name:     "exclude tags",
+			setup:    func(l *YamlFileLoader) { l.SetTagsFilter(nil, []string{"slow"}) },
+			expected: []string{"create order", "get user", "list users"},
+		},
+		{
+			name:     "tags and exclude tags",
+			setup:    func(l *YamlFileLoader) { l.SetTagsFilter([]string{"orders"}, []string{"smoke"}) },
+			expected: []string{"cancel order"},
+		},
+		{
+			name:     "name",
+			setup:    func(l *YamlFileLoader) { l.SetNameFilter(regexp.MustCompile("user")) },
+			expected: []string{"get user", "list users"},
+		},
+		{
+			name: "file glob by path",
+			setup: func(l *YamlFileLoader) {
+				require.NoError(t, l.SetFileGlob("orders/*.yaml"))
+			},
+			expected: []string{"create order", "cancel order"},
+		},
+		{
+			name: "file glob by name",
+			setup: func(l *YamlFileLoader) {
+				require.NoError(t, l.SetFileGlob("*.yml"))
+			},
+			expected: []string{"get user", "list users"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader("testdata/filters")
+			tt.setup(l)
+			assert.Equal(t, tt.expected, loadNames(t, l))
+		})
+	}
+}
+
+func TestLoaderInvalidFileGlob(t *testing.T) {
+	assert.Error(t, NewLoader("testdata/filters").SetFileGlob("[orders"))
+}
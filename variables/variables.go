@@ -14,6 +14,10 @@ type variables map[string]*Variable
 
 var variableRx = regexp.MustCompile(`{{\s*\$(\w+)\s*}}`)
 
+// mockCallVariableRx matches the variables set from the requests received by the mocks,
+// e.g. mock.user-service.count or mock.user-service.last.body
+var mockCallVariableRx = regexp.MustCompile(`{{\s*\$(mock\.[\w-]+\.(?:count|(?:first|last|\d+)\.\w+))\s*}}`)
+
 func New() *Variables {
 	return &Variables{
 		variables: make(variables),
@@ -55,10 +59,21 @@ func (vs *Variables) Apply(t models.TestInterface) models.TestInterface {
 	for _, def := range newTest.GetDatabaseChecks() {
 		def.SetDbQueryString(vs.perform(def.DbQueryString()))
 		def.SetDbResponseJson(vs.performDbResponses(def.DbResponseJson()))
+		if mongoCheck, ok := def.(models.MongoDatabaseCheck); ok && mongoCheck.GetMongoQuery() != nil {
+			mongoCheck.SetMongoQuery(vs.performMongoQuery(mongoCheck.GetMongoQuery()))
+		}
 		dbChecks = append(dbChecks, def)
 	}
 	newTest.SetDatabaseChecks(dbChecks)
 
+	if brokerChecks := newTest.GetBrokerChecks(); brokerChecks != nil {
+		newTest.SetBrokerChecks(vs.performBrokerChecks(brokerChecks))
+	}
+
+	if mockCalls := newTest.GetMockCallChecks(); mockCalls != nil {
+		newTest.SetMockCallChecks(vs.performMockCallChecks(mockCalls))
+	}
+
 	newTest.SetResponses(vs.performResponses(newTest.GetResponses()))
 	newTest.SetHeaders(vs.performHeaders(newTest.Headers()))
 
@@ -66,6 +81,17 @@ func (vs *Variables) Apply(t models.TestInterface) models.TestInterface {
 		newTest.SetForm(vs.performForm(form))
 	}
 
+	if grpcRequest := newTest.GetGrpcRequest(); grpcRequest != nil {
+		newTest.SetGrpcRequest(vs.performGrpcRequest(grpcRequest))
+	}
+
+	if script := newTest.GetScript(); script != nil {
+		newTest.SetScript(vs.performScript(script))
+	}
+	if stderr, ok := newTest.GetExpectedStderr(); ok {
+		newTest.SetExpectedStderr(vs.perform(stderr))
+	}
+
 	for _, definition := range newTest.ServiceMocks() {
 		vs.performInterface(definition)
 	}
@@ -73,6 +99,32 @@ func (vs *Variables) Apply(t models.TestInterface) models.TestInterface {
 	return newTest
 }
 
+// ApplyHook returns a copy of the hook with variables substituted
+func (vs *Variables) ApplyHook(h models.Hook) models.Hook {
+	if vs == nil {
+		return h
+	}
+
+	res := h
+	if h.Request != nil {
+		res.Request = &models.HookRequest{
+			Method:  vs.perform(h.Request.Method),
+			Path:    vs.perform(h.Request.Path),
+			Headers: vs.performHeaders(h.Request.Headers),
+			Body:    vs.perform(h.Request.Body),
+			Status:  h.Request.Status,
+		}
+	}
+	res.DbExec = vs.perform(h.DbExec)
+	if h.Script != nil {
+		res.Script = &models.HookScript{
+			Path:    vs.perform(h.Script.Path),
+			Timeout: h.Script.Timeout,
+		}
+	}
+	return res
+}
+
 // Merge adds given variables to set or overrides existed
 func (vs *Variables) Merge(vars *Variables) {
 	for k, v := range vars.variables {
@@ -80,14 +132,25 @@ func (vs *Variables) Merge(vars *Variables) {
 	}
 }
 
+// Clone returns a copy of the set, so changes of the copy don't affect the original
+func (vs *Variables) Clone() *Variables {
+	res := New()
+	for k, v := range vs.variables {
+		res.variables[k] = v
+	}
+
+	return res
+}
+
 func (vs *Variables) Len() int {
 	return len(vs.variables)
 }
 
 func usedVariables(str string) (res []string) {
-	matches := variableRx.FindAllStringSubmatch(str, -1)
-	for _, match := range matches {
-		res = append(res, match[1])
+	for _, rx := range []*regexp.Regexp{variableRx, mockCallVariableRx} {
+		for _, match := range rx.FindAllStringSubmatch(str, -1) {
+			res = append(res, match[1])
+		}
 	}
 
 	return res
@@ -149,6 +212,69 @@ func (vs *Variables) performForm(form *models.Form) *models.Form {
 	return &models.Form{Files: files}
 }
 
+func (vs *Variables) performGrpcRequest(r *models.GrpcRequest) *models.GrpcRequest {
+	return &models.GrpcRequest{
+		Service:  vs.perform(r.Service),
+		Method:   vs.perform(r.Method),
+		Message:  vs.perform(r.Message),
+		Metadata: vs.performHeaders(r.Metadata),
+	}
+}
+
+func (vs *Variables) performScript(s *models.ScriptRequest) *models.ScriptRequest {
+	args := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		args[i] = vs.perform(arg)
+	}
+	return &models.ScriptRequest{
+		Path:    vs.perform(s.Path),
+		Args:    args,
+		Env:     vs.performHeaders(s.Env),
+		Timeout: s.Timeout,
+	}
+}
+
+func (vs *Variables) performBrokerChecks(checks []models.BrokerCheck) []models.BrokerCheck {
+	res := make([]models.BrokerCheck, len(checks))
+	for i, check := range checks {
+		messages := make([]models.BrokerMessage, len(check.Messages))
+		for j, m := range check.Messages {
+			messages[j] = models.BrokerMessage{
+				Key:     vs.perform(m.Key),
+				Value:   vs.perform(m.Value),
+				Headers: vs.performHeaders(m.Headers),
+			}
+		}
+		check.Topic = vs.perform(check.Topic)
+		check.Messages = messages
+		res[i] = check
+	}
+	return res
+}
+
+func (vs *Variables) performMockCallChecks(checks []models.MockCallCheck) []models.MockCallCheck {
+	res := make([]models.MockCallCheck, len(checks))
+	for i, check := range checks {
+		check.Service = vs.perform(check.Service)
+		check.Method = vs.perform(check.Method)
+		check.Path = vs.perform(check.Path)
+		check.Query = vs.perform(check.Query)
+		check.Headers = vs.performHeaders(check.Headers)
+		check.Body = vs.perform(check.Body)
+		res[i] = check
+	}
+	return res
+}
+
+func (vs *Variables) performMongoQuery(q *models.MongoQuery) *models.MongoQuery {
+	return &models.MongoQuery{
+		Collection: vs.perform(q.Collection),
+		Find:       vs.perform(q.Find),
+		Sort:       vs.perform(q.Sort),
+		Aggregate:  vs.perform(q.Aggregate),
+	}
+}
+
 func (vs *Variables) performHeaders(headers map[string]string) map[string]string {
 
 	res := make(map[string]string)